@@ -2,60 +2,118 @@ package handlers
 
 import (
 	"context"
+	"edge-app/api/helpers"
 	"edge-app/configs"
+	"edge-app/pkg/constant"
+	serviceErrors "edge-app/pkg/errors"
+	"edge-app/pkg/identity"
 	"edge-app/pkg/kafka/consumer"
 	"edge-app/pkg/kafka/producer"
 	"edge-app/pkg/proto"
+	"errors"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-const TopicName string = "test2"
 const Sequence string = "sequence"
 
 func BaseHandler(c *gin.Context) {
 
+	mediaType, err := helpers.Negotiate(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotAcceptable, helpers.CreateBaseResponseWithError(nil, false, helpers.NotAcceptable, err))
+		return
+	}
+
+	req := &proto.PubSubReq{}
+	if status, err := helpers.BindProto(c, req); err != nil {
+		c.AbortWithStatusJSON(status, helpers.CreateBaseResponseWithError(nil, false, helpers.ResultCode(status), err))
+		return
+	}
+
 	cfg := configs.Get()
 	p := producer.NewProducible(cfg)
 	sequence := c.GetHeader(Sequence)
+	if req.Sequence == 0 {
+		req.Sequence, _ = strconv.ParseInt(sequence, 10, 64)
+	}
 
+	ctx := c.Request.Context()
 	headers := []kafka.Header{{Key: Sequence, Value: []byte(sequence)}}
-	p.Produce(TopicName, c.GetHeader(Sequence), &proto.PubSubReq{
-		Sequence: 10,
-	}, headers)
+	if deadline, ok := ctx.Deadline(); ok {
+		headers = append(headers, kafka.Header{Key: constant.Deadline, Value: []byte(strconv.FormatInt(deadline.UnixMilli(), 10))})
+	}
+	identityHeaders, err := identity.NewForwarder(cfg).Headers(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, helpers.CreateBaseResponseWithError(nil, false, helpers.InternalError, err))
+		return
+	}
+	headers = append(headers, identityHeaders...)
+	topic := c.Param("topic")
+	if topic == "" {
+		topic = cfg.Kafka.Topic
+	}
+	if err = p.Produce(ctx, topic, c.GetHeader(Sequence), req, headers); err != nil {
+		abortCancelled(c, err)
+		return
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	ch := make(chan interface{})
-	go getResponse(ctx, ch, cfg)
+	ch := make(chan interface{}, 1)
+	go getResponse(ctx, ch, cfg, topic)
 
 	select {
 	case result := <-ch:
-		c.JSON(http.StatusOK, gin.H{"result": result})
-		cancel()
-		return
-	case <-time.After(time.Second * 30):
-		c.JSON(http.StatusInternalServerError, gin.H{"result": "Server is busy."})
+		if err, failed := result.(error); failed {
+			abortConsumeFailed(c, err)
+			return
+		}
+		helpers.Render(c, http.StatusOK, mediaType, result)
 	case <-ctx.Done():
-		fmt.Println("Client has disconnected.")
+		abortCancelled(c, ctx.Err())
 	}
-
-	cancel()
-	<-ch
 }
-func getResponse(ctx context.Context, ch chan<- interface{}, cfg *configs.Config) {
+
+func getResponse(ctx context.Context, ch chan<- interface{}, cfg *configs.Config, topic string) {
 
 	c := consumer.NewConsumable(cfg)
-	message := c.Consume(TopicName)
+	message, err := c.Consume(ctx, topic)
+	if err != nil {
+		ch <- err
+		return
+	}
+	ch <- message
+}
 
-	select {
-	case <-time.After(time.Millisecond * 1):
-		ch <- message
-	case <-ctx.Done():
-		close(ch)
+// abortConsumeFailed answers a request whose reply could not be consumed.
+// Cancellation keeps its own answer, a reply that cannot be decoded is ours
+// to fix and anything else failed on the way through the broker.
+func abortConsumeFailed(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		abortCancelled(c, err)
+		return
+	}
+	if errors.Is(err, consumer.ErrDeserialization) {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, helpers.CreateBaseResponseWithError(
+			nil, false, helpers.InternalError, &serviceErrors.ServiceError{ErrorDescription: serviceErrors.ErrResponseUnreadable},
+		))
+		return
 	}
+	c.AbortWithStatusJSON(http.StatusBadGateway, helpers.CreateBaseResponseWithError(
+		nil, false, helpers.BadGateway, &serviceErrors.ServiceError{ErrorDescription: serviceErrors.ErrBrokerFailed},
+	))
+}
 
+func abortCancelled(c *gin.Context, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, helpers.CreateBaseResponseWithError(
+			nil, false, helpers.GatewayTimeout, &serviceErrors.ServiceError{ErrorDescription: serviceErrors.ErrDeadlineExceeded},
+		))
+		return
+	}
+	fmt.Println("Client has disconnected.")
+	c.Abort()
 }
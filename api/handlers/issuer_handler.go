@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"edge-app/configs"
+	serviceErrors "edge-app/pkg/errors"
+	"edge-app/pkg/issuer"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthErrors maps issuer failures to the error codes of RFC 6749 5.2.
+var oauthErrors = map[string]struct {
+	status int
+	code   string
+}{
+	serviceErrors.ErrInvalidClient:    {http.StatusUnauthorized, "invalid_client"},
+	serviceErrors.ErrUnsupportedGrant: {http.StatusBadRequest, "unsupported_grant_type"},
+	serviceErrors.ErrInvalidScope:     {http.StatusBadRequest, "invalid_scope"},
+}
+
+// IssuerHandler serves the development token issuer. Its responses follow
+// the OAuth2 and OpenID formats rather than BaseHttpResponse, so that any
+// client library can talk to it.
+type IssuerHandler struct {
+	issuer *issuer.Issuer
+}
+
+func NewIssuerHandler(cfg *configs.Config) *IssuerHandler {
+	devIssuer, err := issuer.NewIssuer(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return &IssuerHandler{issuer: devIssuer}
+}
+
+func (h *IssuerHandler) Token(c *gin.Context) {
+	clientId, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientId, clientSecret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	token, err := h.issuer.Token(c.PostForm("grant_type"), clientId, clientSecret, c.PostForm("scope"))
+	c.Header("Cache-Control", "no-store")
+	if err != nil {
+		var serviceError *serviceErrors.ServiceError
+		if errors.As(err, &serviceError) {
+			if oauthError, exists := oauthErrors[serviceError.ErrorDescription]; exists {
+				c.AbortWithStatusJSON(oauthError.status, gin.H{"error": oauthError.code, "error_description": err.Error()})
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+func (h *IssuerHandler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.issuer.Discovery())
+}
+
+func (h *IssuerHandler) Jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, h.issuer.Jwks())
+}
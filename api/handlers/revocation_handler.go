@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"edge-app/api/helpers"
+	serviceErrors "edge-app/pkg/errors"
+	"edge-app/pkg/revocation"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevocationHandler administers the token deny-list of the edge.
+type RevocationHandler struct {
+	list *revocation.List
+}
+
+func NewRevocationHandler(list *revocation.List) *RevocationHandler {
+	return &RevocationHandler{list: list}
+}
+
+func (h *RevocationHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, helpers.CreateBaseResponse(h.list.Entries(), true, helpers.Success))
+}
+
+func (h *RevocationHandler) Add(c *gin.Context) {
+	var entry revocation.Entry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, helpers.CreateBaseResponseWithError(nil, false, helpers.ValidationError,
+			&serviceErrors.ServiceError{ErrorDescription: serviceErrors.ErrInvalidRequestBody}))
+		return
+	}
+	if err := h.list.Add(entry); err != nil {
+		abortRevocation(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, helpers.CreateBaseResponse(entry, true, helpers.Success))
+}
+
+func (h *RevocationHandler) Remove(c *gin.Context) {
+	if err := h.list.Remove(c.Param("type"), c.Param("value")); err != nil {
+		abortRevocation(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helpers.CreateBaseResponse(nil, true, helpers.Success))
+}
+
+func abortRevocation(c *gin.Context, err error) {
+	var serviceError *serviceErrors.ServiceError
+	if errors.As(err, &serviceError) {
+		switch serviceError.ErrorCode {
+		case serviceErrors.ErrDataNotFound:
+			c.AbortWithStatusJSON(http.StatusNotFound, helpers.CreateBaseResponseWithError(nil, false, helpers.NotFoundError, err))
+			return
+		case serviceErrors.ErrDataMismatch:
+			c.AbortWithStatusJSON(http.StatusConflict, helpers.CreateBaseResponseWithError(nil, false, helpers.ConflictError, err))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, helpers.CreateBaseResponseWithError(nil, false, helpers.ValidationError, err))
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, helpers.CreateBaseResponseWithError(nil, false, helpers.InternalError, err))
+}
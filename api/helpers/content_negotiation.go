@@ -0,0 +1,115 @@
+package helpers
+
+import (
+	"edge-app/pkg/errors"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const MIMEProtobufAlt = "application/protobuf"
+
+// Negotiate picks the response media type from the Accept header. JSON is
+// used when the client does not express a preference.
+func Negotiate(c *gin.Context) (string, error) {
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return binding.MIMEJSON, nil
+	}
+
+	best, bestQ := "", 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		var candidate string
+		switch mediaType {
+		case binding.MIMEPROTOBUF, MIMEProtobufAlt:
+			candidate = binding.MIMEPROTOBUF
+		case binding.MIMEJSON, "application/*", "*/*":
+			candidate = binding.MIMEJSON
+		}
+		if candidate != "" && q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	if best == "" {
+		return "", &errors.ServiceError{ErrorDescription: errors.ErrNotAcceptable}
+	}
+	return best, nil
+}
+
+// BindProto decodes the request body into msg according to Content-Type.
+// An empty body leaves msg untouched.
+func BindProto(c *gin.Context, msg proto.Message) (int, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return http.StatusBadRequest, &errors.ServiceError{ErrorDescription: errors.ErrInvalidRequestBody}
+	}
+	if len(body) == 0 {
+		return http.StatusOK, nil
+	}
+
+	mediaType := binding.MIMEJSON
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return http.StatusUnsupportedMediaType, &errors.ServiceError{ErrorDescription: errors.ErrUnsupportedMediaType}
+		}
+	}
+
+	switch mediaType {
+	case binding.MIMEPROTOBUF, MIMEProtobufAlt:
+		err = proto.Unmarshal(body, msg)
+	case binding.MIMEJSON:
+		err = protojson.Unmarshal(body, msg)
+	default:
+		return http.StatusUnsupportedMediaType, &errors.ServiceError{ErrorDescription: errors.ErrUnsupportedMediaType}
+	}
+	if err != nil {
+		return http.StatusBadRequest, &errors.ServiceError{ErrorDescription: errors.ErrInvalidRequestBody, ExtraData: err.Error()}
+	}
+	return http.StatusOK, nil
+}
+
+// Render writes payload in the negotiated media type. Protobuf messages are
+// written as binary protobuf or wrapped as canonical proto3 JSON inside the
+// BaseHttpResponse envelope.
+func Render(c *gin.Context, status int, mediaType string, payload any) {
+	msg, isProto := payload.(proto.Message)
+	if !isProto {
+		c.JSON(status, CreateBaseResponse(payload, true, Success))
+		return
+	}
+
+	if mediaType == binding.MIMEPROTOBUF {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, CreateBaseResponseWithError(nil, false, InternalError, err))
+			return
+		}
+		c.Data(status, binding.MIMEPROTOBUF, data)
+		return
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, CreateBaseResponseWithError(nil, false, InternalError, err))
+		return
+	}
+	c.JSON(status, CreateBaseResponse(json.RawMessage(data), true, Success))
+}
@@ -8,6 +8,15 @@ const (
 	AuthError       ResultCode = 401
 	ForbiddenError  ResultCode = 403
 	NotFoundError   ResultCode = 404
+	NotAcceptable   ResultCode = 406
+	ConflictError   ResultCode = 409
+	TooLarge        ResultCode = 413
+	UnsupportedType ResultCode = 415
+	Unprocessable   ResultCode = 422
+	TooManyRequests ResultCode = 429
 	CustomRecovery  ResultCode = 500
 	InternalError   ResultCode = 500
+	BadGateway      ResultCode = 502
+	Unavailable     ResultCode = 503
+	GatewayTimeout  ResultCode = 504
 )
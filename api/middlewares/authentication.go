@@ -3,27 +3,113 @@ package middlewares
 import (
 	"edge-app/api/helpers"
 	"edge-app/configs"
+	"edge-app/pkg/audit"
 	"edge-app/pkg/authentication"
 	"edge-app/pkg/constant"
+	"edge-app/pkg/revocation"
+	"edge-app/pkg/route"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authentication(cfg *configs.Config) gin.HandlerFunc {
+// authenticator is everything Authentication builds from the config, swapped
+// as a whole when the config is reloaded and shared by all requests.
+type authenticator struct {
+	cfg                   *configs.Config
+	issuers               *authentication.Issuers
+	apiKeys               *authentication.ApiKeys
+	jwtService            *authentication.Service
+	apiKeyService         *authentication.ApiKeyService
+	certificateService    *authentication.CertificateService
+	introspectionServices map[*authentication.TrustedIssuer]*authentication.IntrospectionService
+}
+
+func newAuthenticator(cfg *configs.Config, revoked *revocation.List) (*authenticator, error) {
+	issuers := authentication.NewIssuers(cfg)
+	apiKeys, err := authentication.NewApiKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	introspectionServices := map[*authentication.TrustedIssuer]*authentication.IntrospectionService{
+		nil: authentication.NewIntrospectionService(cfg, nil, revoked),
+	}
+	for _, issuer := range issuers.All() {
+		introspectionServices[issuer] = authentication.NewIntrospectionService(cfg, issuer, revoked)
+	}
+	return &authenticator{
+		cfg:                   cfg,
+		issuers:               issuers,
+		apiKeys:               apiKeys,
+		jwtService:            authentication.NewAuthenticationService(cfg, issuers, revoked),
+		apiKeyService:         authentication.NewApiKeyService(cfg, apiKeys, revoked),
+		certificateService:    authentication.NewCertificateService(cfg, revoked),
+		introspectionServices: introspectionServices,
+	}, nil
+}
+
+// Authentication checks every credential against the deny-list it is given,
+// which outlives the reloads of the config.
+func Authentication(cfg *configs.Config, revoked *revocation.List) gin.HandlerFunc {
+	state, err := configs.Derive(cfg, func(cfg *configs.Config) (*authenticator, error) {
+		return newAuthenticator(cfg, revoked)
+	})
+	if err != nil {
+		panic(err)
+	}
+	auditor := audit.NewAuditor(cfg)
 	return func(ctx *gin.Context) {
-		template := authentication.Tpl{
-			Impl: authentication.NewAuthenticationService(cfg),
+		current := state.Load()
+		cfg := current.cfg
+		if route.MatchAny(cfg.PublicRoutes, ctx.Request.Method, route.Path(ctx)) {
+			ctx.Set(constant.AccessDecision, constant.AccessPublic)
+			auditor.Record(ctx, audit.StageAuthentication, constant.AccessPublic, nil)
+			ctx.Next()
+			return
+		}
+		credentials := authentication.Credentials{
+			Authorization: ctx.GetHeader(constant.AuthorizationKey),
+			ApiKey:        ctx.GetHeader(current.apiKeys.Header()),
+			Certificate:   authentication.HasCertificate(ctx.Request.TLS),
+		}
+		credential := credentials.Authorization
+		template := authentication.Tpl{}
+		mode, issuer := current.issuers.Resolve(cfg.AuthenticationRoutes, ctx.Request.Method, route.Path(ctx), credentials)
+		switch mode {
+		case authentication.ModeIntrospection:
+			template.Impl = current.introspectionServices[issuer]
+		case authentication.ModeApiKey:
+			template.Impl = current.apiKeyService
+			credential = credentials.ApiKey
+		default:
+			template.Impl = current.jwtService
+		}
+		var (
+			principal *authentication.Principal
+			err       error
+		)
+		if mode == authentication.ModeCertificate {
+			principal, err = current.certificateService.Verify(ctx.Request.TLS)
+		} else {
+			principal, err = template.VerifyTokenTP(credential)
 		}
-		claimMap, err := template.VerifyTokenTP(ctx.GetHeader(constant.AuthorizationKey))
 		if err != nil {
+			ctx.Set(constant.AccessDecision, constant.AccessDenied)
+			auditor.Record(ctx, audit.StageAuthentication, constant.AccessDenied, err)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, helpers.CreateBaseResponseWithError(
 				nil, false, helpers.AuthError, err,
 			))
 			return
 		}
-		ctx.Set(constant.Scope, claimMap[constant.Scope])
-		ctx.Set(constant.Aud, claimMap[constant.Aud])
+		ctx.Set(constant.Scope, principal.Scope)
+		ctx.Set(constant.Aud, principal.ClientId)
+		ctx.Set(constant.Sub, principal.Subject)
+		ctx.Set(constant.Roles, principal.Roles)
+		ctx.Set(constant.Groups, principal.Groups)
+		ctx.Set(constant.Claims, principal.Claims)
+		ctx.Set(constant.Iss, principal.Issuer)
+		ctx.Set(constant.AuthMode, mode)
+		auditor.Record(ctx, audit.StageAuthentication, constant.AccessAllowed, nil)
 		ctx.Next()
 	}
 }
@@ -3,19 +3,78 @@ package middlewares
 import (
 	"edge-app/api/helpers"
 	"edge-app/configs"
+	"edge-app/pkg/audit"
 	"edge-app/pkg/authorization"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/route"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// authorizer pairs the config with the template compiled from it, so that a
+// reload swaps both at once.
+type authorizer struct {
+	cfg      *configs.Config
+	template authorization.Tpl
+}
+
+func newAuthorizer(cfg *configs.Config) (*authorizer, error) {
+	engine, err := authorization.NewPolicyEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	template := authorization.Tpl{Impl: authorization.NewAuthorizationService(cfg)}
+	if !engine.Empty() {
+		template.Impl = authorization.NewPolicyService(cfg, engine)
+	}
+	return &authorizer{cfg: cfg, template: template}, nil
+}
+
 func Authorization(cfg *configs.Config) gin.HandlerFunc {
+	state, err := configs.Derive(cfg, newAuthorizer)
+	if err != nil {
+		panic(err)
+	}
+	auditor := audit.NewAuditor(cfg)
 	return func(ctx *gin.Context) {
-		authorizationService := authorization.NewAuthorizationService(cfg)
-		template := authorization.Tpl{
-			Impl: authorizationService,
+		current := state.Load()
+		cfg, template := current.cfg, current.template
+		path := route.Path(ctx)
+		if route.MatchAny(cfg.PublicRoutes, ctx.Request.Method, path) {
+			ctx.Next()
+			return
+		}
+		if route.MatchAny(cfg.UnscopedRoutes, ctx.Request.Method, path) {
+			ctx.Set(constant.AccessDecision, constant.AccessUnscoped)
+			auditor.Record(ctx, audit.StageAuthorization, constant.AccessUnscoped, nil)
+			ctx.Next()
+			return
 		}
 		if _, err := template.HasRole(ctx); err != nil {
+			ctx.Set(constant.AccessDecision, constant.AccessDenied)
+			auditor.Record(ctx, audit.StageAuthorization, constant.AccessDenied, err)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, helpers.CreateBaseResponseWithError(nil, false, helpers.ForbiddenError, err))
+			return
+		}
+		ctx.Set(constant.AccessDecision, constant.AccessAuthorized)
+		auditor.Record(ctx, audit.StageAuthorization, constant.AccessAuthorized, nil)
+		ctx.Next()
+	}
+}
+
+// RequirePolicy enforces a policy declared when the route is registered, on
+// top of the ones found in config.
+func RequirePolicy(policy authorization.Policy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scope, _ := ctx.Get(constant.Scope)
+		scopeMap := map[string]int{}
+		for _, item := range strings.Fields(fmt.Sprint(scope)) {
+			scopeMap[item] = 0
+		}
+		if err := policy.Check(scopeMap, authorization.RetrieveRoles(ctx), authorization.RetrieveGroups(ctx)); err != nil {
 			ctx.AbortWithStatusJSON(http.StatusForbidden, helpers.CreateBaseResponseWithError(nil, false, helpers.ForbiddenError, err))
 			return
 		}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"bytes"
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/route"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit rejects requests whose body exceeds the limit of their route. It
+// buffers the body itself, so it has to run before any middleware that reads it.
+func BodyLimit(cfg *configs.Config) gin.HandlerFunc {
+	live := configs.Live(cfg)
+	return func(c *gin.Context) {
+		cfg := live.Load()
+		limit := maxBodyBytes(cfg, c.Request.Method, route.Path(c))
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			abortTooLarge(c)
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		c.Request.Body.Close()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, helpers.CreateBaseResponseWithError(
+				nil, false, helpers.ValidationError, &errors.ServiceError{ErrorDescription: errors.ErrInvalidRequestBody},
+			))
+			return
+		}
+		if int64(len(bodyBytes)) > limit {
+			abortTooLarge(c)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		c.Next()
+	}
+}
+
+func maxBodyBytes(cfg *configs.Config, method, path string) int64 {
+	for _, item := range cfg.RequestLimit.Routes {
+		if route.Match(item.Method, item.Path, method, path) {
+			return item.MaxBodyBytes
+		}
+	}
+	return cfg.RequestLimit.MaxBodyBytes
+}
+
+func abortTooLarge(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, helpers.CreateBaseResponseWithError(
+		nil, false, helpers.TooLarge, &errors.ServiceError{ErrorDescription: errors.ErrRequestBodyTooLarge},
+	))
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"edge-app/configs"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cors answers preflight requests itself, so it must be installed before
+// Authentication which would reject them for lacking a token.
+func Cors(cfg *configs.Config) gin.HandlerFunc {
+	allowedMethods := strings.Join(cfg.Cors.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(cfg.Cors.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.Cors.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.Cors.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !isOriginAllowed(cfg.Cors.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if slices.Contains(cfg.Cors.AllowedOrigins, "*") && !cfg.Cors.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.Cors.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", allowedMethods)
+			if allowedHeaders != "" {
+				c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				c.Header("Access-Control-Allow-Headers", requested)
+			}
+			if cfg.Cors.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposedHeaders)
+		}
+		c.Next()
+	}
+}
+
+func isOriginAllowed(allowedOrigins []string, origin string) bool {
+	for _, item := range allowedOrigins {
+		if item == "*" || strings.EqualFold(item, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/idempotency"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Idempotency(cfg *configs.Config) gin.HandlerFunc {
+	store := idempotency.NewStore(cfg.Idempotency.MaxEntries)
+	return idempotent(store, cfg.Idempotency.Ttl, cfg.Idempotency.PendingTtl)
+}
+
+func idempotent(store idempotency.Store, ttl, pendingTtl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(constant.IdempotencyKey)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, helpers.CreateBaseResponseWithError(
+				nil, false, helpers.ValidationError, err,
+			))
+			return
+		}
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		clientId, _ := c.Get(constant.Aud)
+		key := fmt.Sprintf("%v:%s", clientId, idempotencyKey)
+		fingerprint := requestFingerprint(c, bodyBytes)
+
+		record, reserved := store.Reserve(key, fingerprint, pendingTtl)
+		if !reserved {
+			replay(c, record, fingerprint)
+			return
+		}
+
+		// The key is released unless the response was stored, also when the
+		// handler panics and the recovery further up answers instead.
+		completed := false
+		defer func() {
+			if !completed {
+				store.Release(key)
+			}
+		}()
+
+		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		c.Writer = blw
+		c.Next()
+
+		// Only answers are remembered. Server side failures and requests
+		// aborted without one, such as a client that went away, are released
+		// so that the client can retry them.
+		if !c.Writer.Written() || c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+		store.Complete(key, &idempotency.Record{
+			Fingerprint: fingerprint,
+			StatusCode:  c.Writer.Status(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        blw.body.Bytes(),
+		}, ttl)
+		completed = true
+	}
+}
+
+func replay(c *gin.Context, record *idempotency.Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, helpers.CreateBaseResponseWithError(
+			nil, false, helpers.Unprocessable, &errors.ServiceError{ErrorDescription: errors.ErrIdempotencyKeyReused},
+		))
+		return
+	}
+	if !record.Completed {
+		c.AbortWithStatusJSON(http.StatusConflict, helpers.CreateBaseResponseWithError(
+			nil, false, helpers.ConflictError, &errors.ServiceError{ErrorDescription: errors.ErrRequestInProgress},
+		))
+		return
+	}
+	c.Header(constant.IdempotentReplay, "true")
+	c.Data(record.StatusCode, record.ContentType, record.Body)
+	c.Abort()
+}
+
+// requestFingerprint covers the negotiated headers too, so that a response is
+// never replayed in a representation the client did not ask for.
+func requestFingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		c.Request.Method,
+		c.Request.URL.RequestURI(),
+		c.GetHeader("Accept"),
+		c.GetHeader("Content-Type"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"edge-app/pkg/constant"
+	"edge-app/pkg/idempotency"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyEngine serves POST /orders behind the middleware and counts how
+// many times the handler actually ran.
+func idempotencyEngine(store idempotency.Store, handler gin.HandlerFunc) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := gin.New()
+	engine.POST("/orders", idempotent(store, time.Hour, time.Minute), func(c *gin.Context) {
+		calls++
+		handler(c)
+	})
+	return engine, &calls
+}
+
+func idempotentRequest(engine *gin.Engine, key, body string, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	request.Header.Set(constant.IdempotencyKey, key)
+	request.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	engine, calls := idempotencyEngine(idempotency.NewStore(10), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	first := idempotentRequest(engine, "k1", `{"amount":1}`)
+	second := idempotentRequest(engine, "k1", `{"amount":1}`)
+	if *calls != 1 {
+		t.Fatalf("want the handler run once, ran %d times", *calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("want the stored response replayed, got %d %s", second.Code, second.Body.String())
+	}
+	if second.Header().Get(constant.IdempotentReplay) != "true" {
+		t.Error("want the replay marked")
+	}
+	if first.Header().Get(constant.IdempotentReplay) != "" {
+		t.Error("want the first response unmarked")
+	}
+}
+
+func TestIdempotencyConflicts(t *testing.T) {
+	store := idempotency.NewStore(10)
+	engine, calls := idempotencyEngine(store, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	idempotentRequest(engine, "k1", `{"amount":1}`)
+
+	tests := []struct {
+		name    string
+		key     string
+		body    string
+		headers []string
+		status  int
+	}{
+		{"other body", "k1", `{"amount":2}`, nil, http.StatusUnprocessableEntity},
+		{"other accept", "k1", `{"amount":1}`, []string{"Accept", "application/x-protobuf"}, http.StatusUnprocessableEntity},
+		{"other content type", "k1", `{"amount":1}`, []string{"Content-Type", "application/x-protobuf"}, http.StatusUnprocessableEntity},
+		{"in progress", "k2", `{"amount":1}`, nil, http.StatusConflict},
+	}
+	// k2 is held by a request that has not completed yet.
+	store.Reserve("<nil>:k2", requestFingerprintOf(`{"amount":1}`), time.Minute)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if recorder := idempotentRequest(engine, test.key, test.body, test.headers...); recorder.Code != test.status {
+				t.Errorf("want %d, got %d", test.status, recorder.Code)
+			}
+		})
+	}
+	if *calls != 1 {
+		t.Errorf("want the handler run once, ran %d times", *calls)
+	}
+}
+
+// requestFingerprintOf is the fingerprint of the requests idempotentRequest
+// sends with default headers.
+func requestFingerprintOf(body string) string {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return requestFingerprint(c, []byte(body))
+}
+
+func TestIdempotencyReleasesUnansweredRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+	}{
+		// abortCancelled aborts without an answer when the client went away.
+		{"cancelled", func(c *gin.Context) { c.Abort() }},
+		{"server error", func(c *gin.Context) { c.JSON(http.StatusBadGateway, gin.H{}) }},
+		{"panic", func(c *gin.Context) { panic("handler failed") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fail := true
+			engine, calls := idempotencyEngine(idempotency.NewStore(10), func(c *gin.Context) {
+				if fail {
+					fail = false
+					test.handler(c)
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"id": 1})
+			})
+			func() {
+				defer func() { _ = recover() }()
+				idempotentRequest(engine, "k1", `{"amount":1}`)
+			}()
+
+			retry := idempotentRequest(engine, "k1", `{"amount":1}`)
+			if *calls != 2 || retry.Code != http.StatusCreated {
+				t.Errorf("want the retry run again, got %d after %d calls", retry.Code, *calls)
+			}
+			if retry.Header().Get(constant.IdempotentReplay) != "" {
+				t.Error("want nothing replayed for an unanswered request")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"bytes"
 	"edge-app/configs"
+	"edge-app/pkg/constant"
 	"edge-app/pkg/logging"
 	"io"
 	"strings"
@@ -72,6 +73,7 @@ func structuredLogger(logger logging.Logger) gin.HandlerFunc {
 			keys[logging.BodySize] = param.BodySize
 			keys[logging.RequestBody] = string(bodyBytes)
 			keys[logging.ResponseBody] = blw.body.String()
+			keys[logging.Access] = c.GetString(constant.AccessDecision)
 
 			logger.Info(logging.RequestResponse, logging.Api, param.Path, keys)
 		}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/audit"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/ipfilter"
+	"edge-app/pkg/route"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NetworkAccess applies the global and route CIDR rules to c.ClientIP, which
+// only honours X-Forwarded-For when the peer is one of the trusted proxies.
+// It runs first so that denied networks never reach authentication.
+func NetworkAccess(cfg *configs.Config) gin.HandlerFunc {
+	live, filter := configs.Live(cfg), newIpFilter(cfg)
+	auditor := audit.NewAuditor(cfg)
+	return func(ctx *gin.Context) {
+		cfg, filter := live.Load(), filter.Load()
+		if !cfg.NetworkAccess.Enabled {
+			ctx.Next()
+			return
+		}
+		if err := filter.CheckRequest(ctx.Request.Method, route.Path(ctx), ctx.ClientIP()); err != nil {
+			ctx.Set(constant.AccessDecision, constant.AccessDenied)
+			auditor.Record(ctx, audit.StageNetwork, constant.AccessDenied, err)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, helpers.CreateBaseResponseWithError(nil, false, helpers.ForbiddenError, err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ClientNetworkAccess restricts authenticated clients to their registered
+// egress ranges, so it has to run after Authentication.
+func ClientNetworkAccess(cfg *configs.Config) gin.HandlerFunc {
+	live, filter := configs.Live(cfg), newIpFilter(cfg)
+	auditor := audit.NewAuditor(cfg)
+	return func(ctx *gin.Context) {
+		cfg, filter := live.Load(), filter.Load()
+		clientId := ctx.GetString(constant.Aud)
+		if !cfg.NetworkAccess.Enabled || clientId == "" {
+			ctx.Next()
+			return
+		}
+		if err := filter.CheckClient(clientId, ctx.ClientIP()); err != nil {
+			ctx.Set(constant.AccessDecision, constant.AccessDenied)
+			auditor.Record(ctx, audit.StageNetwork, constant.AccessDenied, err)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, helpers.CreateBaseResponseWithError(nil, false, helpers.ForbiddenError, err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func newIpFilter(cfg *configs.Config) *configs.Derived[ipfilter.Filter] {
+	filter, err := configs.Derive(cfg, ipfilter.NewFilter)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/metrics"
+	"edge-app/pkg/ratelimit"
+	"edge-app/pkg/route"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IpRateLimit runs ahead of Authentication and limits each address, so that
+// callers cannot make the edge verify tokens at any rate they like.
+func IpRateLimit(cfg *configs.Config) gin.HandlerFunc {
+	limiter := ratelimit.NewLimiter()
+	live := configs.Live(cfg)
+	return func(c *gin.Context) {
+		cfg := live.Load()
+		rule := toRule(cfg.RateLimit.Ip)
+		if !cfg.RateLimit.Enabled || rule.RequestsPerSecond <= 0 || rule.Burst <= 0 {
+			c.Next()
+			return
+		}
+
+		decision := limiter.Allow(c.ClientIP(), rule)
+		if !decision.Allowed {
+			metrics.RateLimited.WithLabelValues(anonymous, routeLabel(c), c.Request.Method).Inc()
+			abortRateLimited(c, decision)
+			return
+		}
+		c.Next()
+	}
+}
+
+func RateLimit(cfg *configs.Config) gin.HandlerFunc {
+	limiter := ratelimit.NewLimiter()
+	live := configs.Live(cfg)
+	return func(c *gin.Context) {
+		cfg := live.Load()
+		if !cfg.RateLimit.Enabled {
+			c.Next()
+			return
+		}
+
+		// Callers without a client id share the label, their addresses would
+		// make the metric unbounded.
+		clientId, label := c.ClientIP(), anonymous
+		if aud, exists := c.Get(constant.Aud); exists {
+			clientId = fmt.Sprintf("%v", aud)
+			label = clientId
+		}
+		path := route.Path(c)
+		method := c.Request.Method
+
+		rule, scope := rateLimitRule(cfg, clientId, method, path)
+		if rule.RequestsPerSecond <= 0 || rule.Burst <= 0 {
+			c.Next()
+			return
+		}
+
+		decision := limiter.Allow(clientId+" "+scope, rule)
+		c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(decision.Reset))
+		if !decision.Allowed {
+			metrics.RateLimited.WithLabelValues(label, routeLabel(c), method).Inc()
+			abortRateLimited(c, decision)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortRateLimited(c *gin.Context, decision ratelimit.Decision) {
+	c.Header("Retry-After", ceilSeconds(decision.RetryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, helpers.CreateBaseResponseWithError(
+		nil, false, helpers.TooManyRequests, &errors.ServiceError{ErrorDescription: errors.ErrTooManyRequests},
+	))
+}
+
+const anonymous = "anonymous"
+
+// rateLimitRule picks the most specific rule: a route rule for the client,
+// then a route rule for everyone, then the client rule and finally the default.
+// The scope names the bucket: a route rule gets one per client and route
+// pattern, otherwise a client has a single bucket across all routes.
+func rateLimitRule(cfg *configs.Config, clientId, method, path string) (ratelimit.Rule, string) {
+	var routeRule *configs.RouteRateLimit
+	for i, item := range cfg.RateLimit.Routes {
+		if !route.Match(item.Method, item.Path, method, path) {
+			continue
+		}
+		if item.Client == clientId {
+			return toRule(item.RateLimitRule), item.Method + " " + item.Path
+		}
+		if item.Client == "" && routeRule == nil {
+			routeRule = &cfg.RateLimit.Routes[i]
+		}
+	}
+	if routeRule != nil {
+		return toRule(routeRule.RateLimitRule), routeRule.Method + " " + routeRule.Path
+	}
+	if clientRule, exists := cfg.RateLimit.Clients[clientId]; exists {
+		return toRule(clientRule), ""
+	}
+	return toRule(cfg.RateLimit.Default), ""
+}
+
+// routeLabel is the registered route template, requests that matched no
+// route share one label.
+func routeLabel(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+	return "unmatched"
+}
+
+func toRule(rule configs.RateLimitRule) ratelimit.Rule {
+	return ratelimit.Rule{RequestsPerSecond: rule.RequestsPerSecond, Burst: rule.Burst}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
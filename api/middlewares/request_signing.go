@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"bytes"
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/audit"
+	"edge-app/pkg/constant"
+	serviceErrors "edge-app/pkg/errors"
+	"edge-app/pkg/route"
+	"edge-app/pkg/signing"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestSigning runs after Authentication, since the signing key is chosen
+// by the authenticated client. Signed requests are always verified, unsigned
+// ones only pass when neither their client nor their route requires signing.
+func RequestSigning(cfg *configs.Config) gin.HandlerFunc {
+	// Nonces outlive reloads, otherwise a reload would reopen the replay window.
+	nonces := signing.NewNonceStore(cfg.RequestSigning.NonceMaxEntries)
+	live := configs.Live(cfg)
+	verifiers, err := configs.Derive(cfg, func(cfg *configs.Config) (*signing.Verifier, error) {
+		return signing.NewVerifier(cfg, nonces)
+	})
+	if err != nil {
+		panic(err)
+	}
+	auditor := audit.NewAuditor(cfg)
+	return func(ctx *gin.Context) {
+		cfg, verifier := live.Load(), verifiers.Load()
+		path := route.Path(ctx)
+		if !cfg.RequestSigning.Enabled || route.MatchAny(cfg.PublicRoutes, ctx.Request.Method, path) {
+			ctx.Next()
+			return
+		}
+		clientId := ctx.GetString(constant.Aud)
+		signature := ctx.GetHeader(constant.Signature)
+		if signature == "" && !verifier.Required(clientId, ctx.Request.Method, path) {
+			ctx.Next()
+			return
+		}
+
+		body, _ := io.ReadAll(ctx.Request.Body)
+		ctx.Request.Body.Close()
+		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		err := verifier.Verify(clientId, ctx.Request.Method, ctx.Request.URL.RequestURI(), body,
+			ctx.GetHeader(constant.SignatureTime), ctx.GetHeader(constant.SignatureNonce), signature)
+		if err != nil {
+			ctx.Set(constant.AccessDecision, constant.AccessDenied)
+			auditor.Record(ctx, audit.StageSignature, constant.AccessDenied, err)
+			status, code := http.StatusUnauthorized, helpers.AuthError
+			var serviceError *serviceErrors.ServiceError
+			if errors.As(err, &serviceError) && serviceError.ErrorCode == serviceErrors.ErrServiceUnavailable {
+				status, code = http.StatusServiceUnavailable, helpers.Unavailable
+			}
+			ctx.AbortWithStatusJSON(status, helpers.CreateBaseResponseWithError(nil, false, code, err))
+			return
+		}
+		ctx.Next()
+	}
+}
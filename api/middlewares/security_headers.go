@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"edge-app/configs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SecurityHeaders(cfg *configs.Config) gin.HandlerFunc {
+	headers := map[string]string{
+		"Strict-Transport-Security": cfg.SecurityHeaders.Hsts,
+		"Content-Security-Policy":   cfg.SecurityHeaders.ContentSecurityPolicy,
+		"X-Content-Type-Options":    cfg.SecurityHeaders.ContentTypeOptions,
+		"X-Frame-Options":           cfg.SecurityHeaders.FrameOptions,
+		"Referrer-Policy":           cfg.SecurityHeaders.ReferrerPolicy,
+	}
+	return func(c *gin.Context) {
+		for name, value := range headers {
+			if value != "" {
+				c.Header(name, value)
+			}
+		}
+		c.Next()
+	}
+}
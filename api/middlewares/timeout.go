@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"context"
+	"edge-app/api/helpers"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/route"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout attaches a deadline to the request context. Clients may ask for a
+// shorter or longer deadline with the Request-Timeout header, either in
+// milliseconds or as a duration such as "5s", bounded by the route maximum.
+func Timeout(cfg *configs.Config) gin.HandlerFunc {
+	live := configs.Live(cfg)
+	return func(c *gin.Context) {
+		cfg := live.Load()
+		defaultTimeout, maxTimeout := routeTimeouts(cfg, c.Request.Method, route.Path(c))
+		timeout := defaultTimeout
+
+		if value := c.GetHeader(constant.RequestTimeout); value != "" {
+			requested, err := parseTimeout(value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, helpers.CreateBaseResponseWithError(
+					nil, false, helpers.ValidationError, err,
+				))
+				return
+			}
+			timeout = requested
+		}
+		if maxTimeout > 0 && (timeout <= 0 || timeout > maxTimeout) {
+			timeout = maxTimeout
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// routeTimeouts returns the default and the maximum of the first matching
+// route. A route without a maximum of its own is bounded by the global one.
+func routeTimeouts(cfg *configs.Config, method, path string) (time.Duration, time.Duration) {
+	for _, item := range cfg.Timeout.Routes {
+		if route.Match(item.Method, item.Path, method, path) {
+			if item.Max == 0 {
+				return item.Default, cfg.Timeout.Max
+			}
+			return item.Default, item.Max
+		}
+	}
+	return cfg.Timeout.Default, cfg.Timeout.Max
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil && millis > 0 {
+		return time.Duration(millis) * time.Millisecond, nil
+	}
+	if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+		return timeout, nil
+	}
+	return 0, &errors.ServiceError{ErrorDescription: errors.ErrInvalidTimeout, OriginalValue: value}
+}
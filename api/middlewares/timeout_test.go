@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"edge-app/configs"
+	"testing"
+	"time"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	cfg := &configs.Config{Timeout: configs.Timeout{
+		Default: 30 * time.Second,
+		Max:     time.Minute,
+		Routes: []configs.RouteTimeout{
+			{Method: "GET", Path: "/api/v1/reports", Default: 2 * time.Minute, Max: 5 * time.Minute},
+			{Method: "*", Path: "/api/v1/*", Default: 10 * time.Second},
+		},
+	}}
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantDefault time.Duration
+		wantMax     time.Duration
+	}{
+		{"route with its own max", "GET", "/api/v1/reports", 2 * time.Minute, 5 * time.Minute},
+		{"route without a max", "POST", "/api/v1/orders", 10 * time.Second, time.Minute},
+		{"no route", "GET", "/health", 30 * time.Second, time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotDefault, gotMax := routeTimeouts(cfg, test.method, test.path)
+			if gotDefault != test.wantDefault || gotMax != test.wantMax {
+				t.Errorf("want %v/%v, got %v/%v", test.wantDefault, test.wantMax, gotDefault, gotMax)
+			}
+		})
+	}
+}
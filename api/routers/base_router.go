@@ -2,9 +2,17 @@ package routers
 
 import (
 	"edge-app/api/handlers"
+	"edge-app/api/middlewares"
+	"edge-app/configs"
 	"github.com/gin-gonic/gin"
 )
 
-func BaseRouter(r *gin.RouterGroup) {
-	r.GET("/", handlers.BaseHandler)
+func BaseRouter(r *gin.RouterGroup, cfg *configs.Config) {
+	idempotency := middlewares.Idempotency(cfg)
+	r.GET("/", idempotency, handlers.BaseHandler)
+	r.POST("/", idempotency, handlers.BaseHandler)
+	// The default topic comes from kafka.topic, these routes name their own
+	// and are what policy rules over topic see.
+	r.GET("/topics/:topic", idempotency, handlers.BaseHandler)
+	r.POST("/topics/:topic", idempotency, handlers.BaseHandler)
 }
@@ -0,0 +1,15 @@
+package routers
+
+import (
+	"edge-app/api/handlers"
+	"edge-app/configs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Issuer(r *gin.RouterGroup, cfg *configs.Config) {
+	handler := handlers.NewIssuerHandler(cfg)
+	r.GET("/.well-known/openid-configuration", handler.Discovery)
+	r.GET("/jwks", handler.Jwks)
+	r.POST("/token", handler.Token)
+}
@@ -0,0 +1,20 @@
+package routers
+
+import (
+	"edge-app/api/handlers"
+	"edge-app/api/middlewares"
+	"edge-app/pkg/authorization"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/revocation"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Revocation(r *gin.RouterGroup, list *revocation.List) {
+	handler := handlers.NewRevocationHandler(list)
+	// Required here as well, so the admin routes never rely on routePolicies.
+	r.Use(middlewares.RequirePolicy(authorization.Policy{Roles: []string{constant.AdminRole}}))
+	r.GET("/revocations", handler.List)
+	r.POST("/revocations", handler.Add)
+	r.DELETE("/revocations/:type/:value", handler.Remove)
+}
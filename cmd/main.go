@@ -9,8 +9,11 @@ import (
 	"edge-app/pkg/kafka/producer"
 	"edge-app/pkg/logging"
 	"edge-app/pkg/metrics"
+	"edge-app/pkg/mtls"
+	"edge-app/pkg/revocation"
 	"edge-app/pkg/traces"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 
@@ -36,18 +39,42 @@ func main() {
 		}
 	}(context.Background())
 
+	// The deny-list is shared by every authentication mode and the admin
+	// routes, and like its Kafka topic it lives as long as the process.
+	revoked, err := revocation.NewList(cfg)
+	if err != nil {
+		panic(err)
+	}
+
 	gin.SetMode(cfg.Server.RunMode)
 	r := gin.New()
+	// Without trusted proxies gin believes X-Forwarded-For from any peer.
+	if err := r.SetTrustedProxies(cfg.NetworkAccess.TrustedProxies); err != nil {
+		panic(err)
+	}
 
+	r.Use(middlewares.BodyLimit(cfg))
 	r.Use(middlewares.DefaultLogger(cfg))
-	r.Use(middlewares.Authentication(cfg))
+	r.Use(middlewares.SecurityHeaders(cfg))
+	r.Use(middlewares.NetworkAccess(cfg))
+	r.Use(middlewares.IpRateLimit(cfg))
+	r.Use(middlewares.Cors(cfg))
+	r.Use(middlewares.Authentication(cfg, revoked))
+	r.Use(middlewares.ClientNetworkAccess(cfg))
+	r.Use(middlewares.RequestSigning(cfg))
 	r.Use(middlewares.Authorization(cfg))
+	r.Use(middlewares.RateLimit(cfg))
+	r.Use(middlewares.Timeout(cfg))
 	r.Use(middlewares.Prometheus())
 	r.Use(otelgin.Middleware(cfg.Application.Name))
 	r.Use(gin.CustomRecovery(middlewares.ErrorHandler))
 
 	registerPrometheus()
-	registerRouts(r)
+	registerRouts(r, cfg, revoked)
+	if err = configs.RegisterRoutes(cfg, servedRoutes(r)); err != nil {
+		panic(err)
+	}
+	watchConfig(cfg)
 
 	p := producer.NewProducible(cfg)
 	defer p.Close()
@@ -55,12 +82,30 @@ func main() {
 	c := consumer.NewConsumable(cfg)
 	defer c.Close()
 
-	err := r.Run(":" + strconv.Itoa(cfg.Port))
+	err = run(r, cfg)
 	if err != nil {
 		panic(err)
 	}
 }
 
+func run(r *gin.Engine, cfg *configs.Config) error {
+	addr := ":" + strconv.Itoa(cfg.Port)
+	if !cfg.Server.Tls.Enabled {
+		return r.Run(addr)
+	}
+
+	tlsConfig, err := mtls.ServerConfig(cfg.Server.Tls)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   r,
+		TLSConfig: tlsConfig,
+	}
+	return server.ListenAndServeTLS(cfg.Server.Tls.CertFile, cfg.Server.Tls.KeyFile)
+}
+
 func setUpBanner(cfg *configs.Config) {
 	file, err := os.Open(cfg.Banner.FilePath)
 	if err != nil {
@@ -76,11 +121,44 @@ func setUpBanner(cfg *configs.Config) {
 	banner.Init(colorable.NewColorableStdout(), true, true, file)
 }
 
-func registerRouts(r *gin.Engine) {
+func registerRouts(r *gin.Engine, cfg *configs.Config, revoked *revocation.List) {
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	api := r.Group("/api")
 	routers.Health(api.Group("/v1"))
-	routers.BaseRouter(api.Group("/v1"))
+	routers.BaseRouter(api.Group("/v1"), cfg)
+	routers.Revocation(api.Group("/admin"), revoked)
+	if cfg.DevIssuer.Enabled {
+		routers.Issuer(r.Group("/issuer"), cfg)
+	}
+}
+
+func servedRoutes(r *gin.Engine) []configs.Route {
+	routes := make([]configs.Route, 0, len(r.Routes()))
+	for _, info := range r.Routes() {
+		routes = append(routes, configs.Route{Method: info.Method, Path: info.Path})
+	}
+	return routes
+}
+
+func watchConfig(cfg *configs.Config) {
+	logger := logging.NewLogger(cfg)
+	configs.Subscribe(func(next *configs.Config) (func(), error) {
+		return func() { logging.SetLevel(next) }, nil
+	})
+
+	err := configs.Watch(func(restartRequired []string, err error) {
+		if err != nil {
+			logger.Error(logging.General, logging.Reload, err.Error(), nil)
+			return
+		}
+		logger.Info(logging.General, logging.Reload, "configuration reloaded", nil)
+		for _, path := range restartRequired {
+			logger.Warn(logging.General, logging.Reload, path+" changed and takes effect after a restart", nil)
+		}
+	})
+	if err != nil {
+		logger.Error(logging.General, logging.Reload, err.Error(), nil)
+	}
 }
 
 func registerPrometheus() {
@@ -95,4 +173,14 @@ func registerPrometheus() {
 	if err != nil {
 		logger.Error(logging.Prometheus, logging.Startup, err.Error(), nil)
 	}
+
+	err = prometheus.Register(metrics.RateLimited)
+	if err != nil {
+		logger.Error(logging.Prometheus, logging.Startup, err.Error(), nil)
+	}
+
+	err = prometheus.Register(metrics.AuthCache)
+	if err != nil {
+		logger.Error(logging.Prometheus, logging.Startup, err.Error(), nil)
+	}
 }
@@ -2,8 +2,12 @@ package configs
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,6 +16,8 @@ const (
 	DEV  = "dev"
 	TEST = "test"
 	PROD = "prod"
+
+	wildcard = "*"
 )
 
 type Config struct {
@@ -21,8 +27,29 @@ type Config struct {
 	Otel
 	Banner
 	Kafka
-	PublicKeys  map[string]string `mapstructure:"publicKeys"`
-	ValidScopes map[string]string `mapstructure:"validScopes"`
+	Idempotency
+	RateLimit    `mapstructure:"rateLimit"`
+	RequestLimit `mapstructure:"requestLimit"`
+	Timeout
+	Cors
+	SecurityHeaders      `mapstructure:"securityHeaders"`
+	TrustedIssuers       []TrustedIssuer       `mapstructure:"trustedIssuers" validate:"dive"`
+	AuthenticationRoutes []AuthenticationRoute `mapstructure:"authenticationRoutes" validate:"dive"`
+	ValidScopes          map[string]string     `mapstructure:"validScopes" validate:"min=1"`
+	ValidRoles           map[string]string     `mapstructure:"validRoles"`
+	RoutePolicies        []RoutePolicy         `mapstructure:"routePolicies" validate:"dive"`
+	PolicyRules          []PolicyRule          `mapstructure:"policyRules" validate:"dive"`
+	Mtls                 Mtls                  `mapstructure:"mtls"`
+	ApiKeys              ApiKeys               `mapstructure:"apiKeys"`
+	PublicRoutes         []Route               `mapstructure:"publicRoutes" validate:"dive"`
+	UnscopedRoutes       []Route               `mapstructure:"unscopedRoutes" validate:"dive"`
+	IdentityForwarding   IdentityForwarding    `mapstructure:"identityForwarding"`
+	TokenCache           TokenCache            `mapstructure:"tokenCache"`
+	Audit                Audit                 `mapstructure:"audit"`
+	Revocation           Revocation            `mapstructure:"revocation"`
+	DevIssuer            DevIssuer             `mapstructure:"devIssuer"`
+	RequestSigning       RequestSigning        `mapstructure:"requestSigning"`
+	NetworkAccess        NetworkAccess         `mapstructure:"networkAccess"`
 }
 
 type Application struct {
@@ -30,16 +57,25 @@ type Application struct {
 }
 
 type Server struct {
-	Port    int
-	RunMode string
+	Port    int    `validate:"min=1,max=65535"`
+	RunMode string `validate:"oneof=debug release test"`
+	Tls     Tls
+}
+
+type Tls struct {
+	Enabled      bool
+	CertFile     string `validate:"required_if=Enabled true"`
+	KeyFile      string `validate:"required_if=Enabled true"`
+	ClientCaFile string
+	ClientAuth   string `validate:"omitempty,oneof=none verify_if_given require"`
 }
 
 type Logging struct {
-	FilePath string
-	FileName string
+	FilePath string `validate:"required_if=Console false"`
+	FileName string `validate:"required_if=Console false"`
 	Encoding string
-	Level    string
-	Logger   string
+	Level    string `validate:"oneof=debug info warn error fatal"`
+	Logger   string `validate:"oneof=zerolog"`
 	Console  bool
 }
 
@@ -53,18 +89,19 @@ type Otel struct {
 }
 
 type Kafka struct {
-	BootstrapServers      string
+	BootstrapServers      string `validate:"required"`
+	Topic                 string `validate:"required"`
 	SchemaRegistry        string
 	MessageMaxBytes       int
 	AllowAutoCreateTopics bool
-	SecurityProtocol      string
+	SecurityProtocol      string `validate:"omitempty,oneof=plaintext ssl sasl_plaintext sasl_ssl"`
 	Consumer
 	Producer
 }
 
 type Consumer struct {
-	GroupID           string
-	AutoOffsetReset   string
+	GroupID           string `validate:"required"`
+	AutoOffsetReset   string `validate:"omitempty,oneof=smallest earliest beginning largest latest end error"`
 	MaxPollIntervalMs int
 	EnableAutoCommit  bool
 }
@@ -75,21 +112,336 @@ type Producer struct {
 	Retries           int
 }
 
+// Idempotency keeps completed responses for Ttl. A request still in progress
+// holds its key for PendingTtl only, which should outlast the longest timeout,
+// so that a key is not blocked for long when its request never completes.
+type Idempotency struct {
+	Ttl        time.Duration `validate:"gt=0"`
+	PendingTtl time.Duration `mapstructure:"pendingTtl" validate:"gt=0"`
+	MaxEntries int           `validate:"gte=0"`
+}
+
+// TokenCache keeps the claims of tokens whose signature has already been
+// verified, until they expire but never longer than MaxTtl.
+type TokenCache struct {
+	Enabled    bool
+	MaxEntries int           `validate:"gte=0"`
+	MaxTtl     time.Duration `validate:"required_if=Enabled true"`
+}
+
+// Audit writes every access decision to FilePath, or stdout when empty, and
+// optionally publishes it to a Kafka topic.
+type Audit struct {
+	Enabled  bool
+	FilePath string `mapstructure:"filePath"`
+	Kafka    AuditKafka
+}
+
+type AuditKafka struct {
+	Enabled bool
+	Topic   string `validate:"required_if=Enabled true"`
+}
+
+// Revocation is the deny-list of tokens. FilePath holds a JSON array of
+// entries and is reloaded every RefreshInterval when it changed.
+type Revocation struct {
+	Enabled         bool
+	FilePath        string        `mapstructure:"filePath"`
+	RefreshInterval time.Duration `mapstructure:"refreshInterval" validate:"gte=0"`
+	Kafka           RevocationKafka
+}
+
+type RevocationKafka struct {
+	Enabled bool
+	Topic   string `validate:"required_if=Enabled true"`
+}
+
+// DevIssuer is the built-in token issuer for local development and tests,
+// served under /issuer. It has no place in production.
+type DevIssuer struct {
+	Enabled   bool
+	Issuer    string        `validate:"required_if=Enabled true"`
+	Algorithm string        `validate:"omitempty,oneof=RS256 ES256"`
+	KeyFile   string        `mapstructure:"keyFile"`
+	TokenTtl  time.Duration `mapstructure:"tokenTtl" validate:"gte=0"`
+	Clients   []DevClient   `validate:"dive"`
+}
+
+type DevClient struct {
+	ClientId     string `mapstructure:"clientId" validate:"required"`
+	ClientSecret string `mapstructure:"clientSecret" validate:"required"`
+	Scopes       []string
+	Roles        []string
+	Audiences    []string
+}
+
+// RequestSigning verifies requests signed by partners and rejects replays.
+// Timestamps may be off by MaxSkew and nonces are kept for twice as long.
+// NonceMaxEntries has to hold every nonce of such a window, when it is full
+// signed requests are refused with 503 rather than forgetting live nonces.
+type RequestSigning struct {
+	Enabled         bool
+	MaxSkew         time.Duration   `mapstructure:"maxSkew" validate:"gte=0"`
+	NonceMaxEntries int             `mapstructure:"nonceMaxEntries" validate:"gte=0"`
+	Routes          []Route         `validate:"dive"`
+	Clients         []SigningClient `validate:"dive"`
+}
+
+// SigningClient holds either the HMAC Secret or the PEM PublicKey of a
+// client. Required makes signing mandatory on all of its requests.
+type SigningClient struct {
+	ClientId  string `mapstructure:"clientId" validate:"required"`
+	Secret    string `validate:"required_without=PublicKey,expanded"`
+	PublicKey string `mapstructure:"publicKey"`
+	Required  bool
+}
+
+// NetworkAccess lists CIDR ranges, or single addresses, allowed and denied
+// globally, per route and per client. TrustedProxies are the peers whose
+// X-Forwarded-For is believed when resolving the client address.
+type NetworkAccess struct {
+	Enabled        bool
+	TrustedProxies []string `mapstructure:"trustedProxies" validate:"dive,cidr|ip"`
+	NetworkRule    `mapstructure:",squash"`
+	Routes         []RouteNetworkAccess  `validate:"dive"`
+	Clients        []ClientNetworkAccess `validate:"dive"`
+}
+
+type NetworkRule struct {
+	Allow []string `validate:"dive,cidr|ip"`
+	Deny  []string `validate:"dive,cidr|ip"`
+}
+
+type RouteNetworkAccess struct {
+	Method      string
+	Path        string `validate:"required"`
+	NetworkRule `mapstructure:",squash"`
+}
+
+type ClientNetworkAccess struct {
+	ClientId    string `mapstructure:"clientId" validate:"required"`
+	NetworkRule `mapstructure:",squash"`
+}
+
+// RateLimit limits every address by Ip before authentication, so that floods
+// of bad tokens are cheap, and then every client by the other rules.
+type RateLimit struct {
+	Enabled bool
+	Ip      RateLimitRule
+	Default RateLimitRule
+	Clients map[string]RateLimitRule `validate:"dive"`
+	Routes  []RouteRateLimit         `validate:"dive"`
+}
+
+type RateLimitRule struct {
+	RequestsPerSecond float64 `validate:"gte=0"`
+	Burst             int     `validate:"gte=0"`
+}
+
+type RouteRateLimit struct {
+	Method        string
+	Path          string `validate:"required"`
+	Client        string
+	RateLimitRule `mapstructure:",squash"`
+}
+
+type RequestLimit struct {
+	MaxBodyBytes int64               `validate:"gte=0"`
+	Routes       []RouteRequestLimit `validate:"dive"`
+}
+
+type RouteRequestLimit struct {
+	Method       string
+	Path         string `validate:"required"`
+	MaxBodyBytes int64  `validate:"gte=0"`
+}
+
+type Timeout struct {
+	Default time.Duration  `validate:"gt=0"`
+	Max     time.Duration  `validate:"gtefield=Default"`
+	Routes  []RouteTimeout `validate:"dive"`
+}
+
+// RouteTimeout overrides the timeouts of matching routes. Without a Max of
+// its own the route falls back to timeout.max.
+type RouteTimeout struct {
+	Method  string
+	Path    string        `validate:"required"`
+	Default time.Duration `validate:"gte=0"`
+	Max     time.Duration `validate:"omitempty,gtefield=Default"`
+}
+
+type Cors struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration `validate:"gte=0"`
+}
+
+type SecurityHeaders struct {
+	Hsts                  string
+	ContentSecurityPolicy string
+	ContentTypeOptions    string
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+type Jwks struct {
+	Url                string `validate:"omitempty,url"`
+	FilePath           string
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+	Timeout            time.Duration
+}
+
+type TrustedIssuer struct {
+	Issuer        string `validate:"required"`
+	Mode          string `validate:"omitempty,oneof=jwt introspection"`
+	Jwks          Jwks
+	PublicKeys    map[string]string `mapstructure:"publicKeys"`
+	Introspection Introspection
+	Audiences     []string
+	Algorithms    []string      `validate:"dive,oneof=RS256 RS384 RS512 PS256 ES256 ES384 EdDSA"`
+	Leeway        time.Duration `validate:"gte=0"`
+	ClaimMappings ClaimMappings `mapstructure:"claimMappings"`
+}
+
+type Introspection struct {
+	Url             string `validate:"omitempty,url"`
+	ClientId        string `mapstructure:"clientId"`
+	ClientSecret    string `mapstructure:"clientSecret" validate:"expanded"`
+	Timeout         time.Duration
+	CacheTtl        time.Duration `mapstructure:"cacheTtl"`
+	CacheMaxEntries int           `mapstructure:"cacheMaxEntries"`
+}
+
+type AuthenticationRoute struct {
+	Method string
+	Path   string `validate:"required"`
+	Mode   string `validate:"oneof=jwt introspection mtls apikey"`
+	Issuer string
+}
+
+// ClaimMappings name the claims of the principal. Roles and Groups are dotted
+// claim paths such as realm_access.roles, where a {client} segment stands for
+// the resolved client id as in resource_access.{client}.roles.
+type ClaimMappings struct {
+	ClientId string `mapstructure:"clientId"`
+	Subject  string
+	Scope    string
+	Roles    []string
+	Groups   []string
+}
+
+type RoutePolicy struct {
+	Method string
+	Path   string `validate:"required"`
+	Scopes []string
+	Roles  []string
+	Groups []string
+	Match  string `validate:"omitempty,oneof=any all"`
+}
+
+type PolicyRule struct {
+	Name       string `validate:"required"`
+	Method     string
+	Path       string
+	Expression string `validate:"required"`
+}
+
+type Mtls struct {
+	Clients []CertificateClient `validate:"dive"`
+}
+
+type CertificateClient struct {
+	Subject  string `validate:"required_without=San"`
+	San      string
+	ClientId string `mapstructure:"clientId" validate:"required"`
+	Scopes   []string
+}
+
+type ApiKeys struct {
+	Header   string
+	FilePath string
+	Keys     []ApiKey `validate:"dive"`
+}
+
+type ApiKey struct {
+	Id        string   `json:"id" validate:"required"`
+	Hash      string   `json:"hash" validate:"required"`
+	ClientId  string   `json:"clientId" mapstructure:"clientId" validate:"required"`
+	Scopes    []string `json:"scopes"`
+	NotBefore string   `json:"notBefore" mapstructure:"notBefore" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	ExpiresAt string   `json:"expiresAt" mapstructure:"expiresAt" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Enabled   bool     `json:"enabled"`
+}
+
+type Route struct {
+	Method string
+	Path   string `validate:"required"`
+}
+
+// Matches reports whether the request method and path satisfy the patterns
+// of the route. An empty or "*" method matches any method and a path ending
+// with "*" matches every path sharing its prefix.
+func (r Route) Matches(method, path string) bool {
+	if r.Method != "" && r.Method != wildcard && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	if strings.HasSuffix(r.Path, wildcard) {
+		return strings.HasPrefix(path, strings.TrimSuffix(r.Path, wildcard))
+	}
+	return r.Path == path
+}
+
+type IdentityForwarding struct {
+	Enabled      bool
+	HeaderPrefix string `mapstructure:"headerPrefix"`
+	Claims       []string
+	Assertion    IdentityAssertion
+}
+
+type IdentityAssertion struct {
+	Enabled bool
+	Type    string `validate:"omitempty,oneof=jwt hmac"`
+	Secret  string `validate:"required_if=Enabled true,expanded"`
+	Issuer  string
+	Ttl     time.Duration
+}
+
 type Banner struct {
 	FilePath string
 }
 
+// Get returns the config in effect, loading it on first use. Later changes
+// come in through Reload.
 func Get() *Config {
+	loadOnce.Do(func() {
+		cfg, err := read()
+		if err != nil {
+			log.Fatalf("%v \n", err)
+		}
+		current.CompareAndSwap(nil, cfg)
+	})
+	return current.Load()
+}
+
+func read() (*Config, error) {
 	path := getPath(os.Getenv("APP_ENV"))
 	v, err := load(path, "yml")
 	if err != nil {
-		log.Fatalf("error in loading config %v \n", err)
+		return nil, fmt.Errorf("error in loading config %w", err)
 	}
 	cfg, err := parse(v)
 	if err != nil {
-		log.Fatalf("error in parsing config %v \n", err)
+		return nil, fmt.Errorf("error in parsing config %w", err)
+	}
+	if err = Validate(cfg); err != nil {
+		return nil, fmt.Errorf("error in validating config %s :\n%w", path, err)
 	}
-	return cfg
+	return cfg, nil
 }
 
 func getPath(env string) (path string) {
@@ -118,10 +470,39 @@ func load(filename string, fileType string) (*viper.Viper, error) {
 		if errors.As(err, &configFileNotFoundError) {
 			return nil, errors.New("config file not found")
 		}
+		return v, nil
+	}
+
+	// viper keeps ${VAR} as written, so secrets are expanded from the
+	// environment before the file is parsed.
+	raw, err := os.ReadFile(v.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+	if err = v.ReadConfig(strings.NewReader(expandEnv(string(raw)))); err != nil {
+		return nil, err
 	}
 	return v, nil
 }
 
+var envReference = regexp.MustCompile(`\$\{(\w+)(:-([^}]*))?\}`)
+
+// expandEnv replaces ${VAR} with the environment variable and ${VAR:-value}
+// with value when VAR is unset. An unset ${VAR} without a default is left as
+// written, for Validate to reject.
+func expandEnv(raw string) string {
+	return envReference.ReplaceAllStringFunc(raw, func(reference string) string {
+		match := envReference.FindStringSubmatch(reference)
+		if value, exists := os.LookupEnv(match[1]); exists {
+			return value
+		}
+		if match[2] != "" {
+			return match[3]
+		}
+		return reference
+	})
+}
+
 func parse(v *viper.Viper) (cfg *Config, err error) {
 	err = v.Unmarshal(&cfg)
 	if err != nil {
@@ -0,0 +1,157 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	current     atomic.Pointer[Config]
+	loadOnce    sync.Once
+	reloadMu    sync.Mutex
+	subscribers []func(next *Config) (apply func(), err error)
+)
+
+// staticSettings are read once at startup, by the server, the Kafka clients,
+// the logger or the sinks and stores built around them. Changing one takes a
+// restart, which Reload reports.
+var staticSettings = []struct {
+	path  string
+	value func(cfg *Config) interface{}
+}{
+	{"application.name", func(cfg *Config) interface{} { return cfg.Application }},
+	{"server.port", func(cfg *Config) interface{} { return cfg.Server.Port }},
+	{"server.runMode", func(cfg *Config) interface{} { return cfg.Server.RunMode }},
+	{"server.tls", func(cfg *Config) interface{} { return cfg.Server.Tls }},
+	{"logging.filePath", func(cfg *Config) interface{} { return cfg.Logging.FilePath }},
+	{"logging.fileName", func(cfg *Config) interface{} { return cfg.Logging.FileName }},
+	{"logging.logger", func(cfg *Config) interface{} { return cfg.Logging.Logger }},
+	{"logging.console", func(cfg *Config) interface{} { return cfg.Logging.Console }},
+	{"otel", func(cfg *Config) interface{} { return cfg.Otel }},
+	{"kafka.bootstrapServers", func(cfg *Config) interface{} { return cfg.Kafka.BootstrapServers }},
+	{"kafka.schemaRegistry", func(cfg *Config) interface{} { return cfg.Kafka.SchemaRegistry }},
+	{"kafka.messageMaxBytes", func(cfg *Config) interface{} { return cfg.Kafka.MessageMaxBytes }},
+	{"kafka.allowAutoCreateTopics", func(cfg *Config) interface{} { return cfg.Kafka.AllowAutoCreateTopics }},
+	{"kafka.securityProtocol", func(cfg *Config) interface{} { return cfg.Kafka.SecurityProtocol }},
+	{"kafka.consumer", func(cfg *Config) interface{} { return cfg.Kafka.Consumer }},
+	{"kafka.producer", func(cfg *Config) interface{} { return cfg.Kafka.Producer }},
+	{"idempotency", func(cfg *Config) interface{} { return cfg.Idempotency }},
+	{"cors", func(cfg *Config) interface{} { return cfg.Cors }},
+	{"securityHeaders", func(cfg *Config) interface{} { return cfg.SecurityHeaders }},
+	{"audit", func(cfg *Config) interface{} { return cfg.Audit }},
+	{"revocation", func(cfg *Config) interface{} { return cfg.Revocation }},
+	{"devIssuer", func(cfg *Config) interface{} { return cfg.DevIssuer }},
+	{"requestSigning.nonceMaxEntries", func(cfg *Config) interface{} { return cfg.RequestSigning.NonceMaxEntries }},
+	{"networkAccess.trustedProxies", func(cfg *Config) interface{} { return cfg.NetworkAccess.TrustedProxies }},
+}
+
+// Subscribe registers a subsystem to be rebuilt on reload. prepare gets the
+// new config before it is swapped in and may refuse it with an error, which
+// aborts the whole reload. The returned apply runs once the swap is done.
+func Subscribe(prepare func(next *Config) (apply func(), err error)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	subscribers = append(subscribers, prepare)
+}
+
+// Reload reads and validates the config file again, lets every subscriber
+// prepare for it and then swaps it in. It returns the changed settings that
+// only take effect after a restart.
+func Reload() (restartRequired []string, err error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := read()
+	if err != nil {
+		return nil, err
+	}
+	applies := make([]func(), 0, len(subscribers))
+	var errs []error
+	for _, prepare := range subscribers {
+		apply, err := prepare(next)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		applies = append(applies, apply)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	previous := current.Swap(next)
+	for _, apply := range applies {
+		apply()
+	}
+	for _, setting := range staticSettings {
+		if previous != nil && !reflect.DeepEqual(setting.value(previous), setting.value(next)) {
+			restartRequired = append(restartRequired, setting.path)
+		}
+	}
+	return restartRequired, nil
+}
+
+// Watch reloads when the config file changes and on SIGHUP, handing every
+// outcome to report.
+func Watch(report func(restartRequired []string, err error)) error {
+	v, err := load(getPath(os.Getenv("APP_ENV")), "yml")
+	if err != nil {
+		return err
+	}
+	v.OnConfigChange(func(fsnotify.Event) {
+		report(Reload())
+	})
+	v.WatchConfig()
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			report(Reload())
+		}
+	}()
+	return nil
+}
+
+// Derived is state built from the config, such as key sets or compiled
+// policies, that is rebuilt and swapped whenever the config is reloaded.
+type Derived[T any] struct {
+	value atomic.Pointer[T]
+}
+
+func Derive[T any](cfg *Config, build func(cfg *Config) (*T, error)) (*Derived[T], error) {
+	value, err := build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	derived := &Derived[T]{}
+	derived.value.Store(value)
+	Subscribe(func(next *Config) (func(), error) {
+		value, err := build(next)
+		if err != nil {
+			return nil, fmt.Errorf("%T: %w", value, err)
+		}
+		return func() { derived.value.Store(value) }, nil
+	})
+	return derived, nil
+}
+
+// Live follows cfg and then every config reloaded after it.
+func Live(cfg *Config) *Derived[Config] {
+	live, _ := Derive(cfg, func(next *Config) (*Config, error) {
+		return next, nil
+	})
+	return live
+}
+
+func (d *Derived[T]) Load() *T {
+	return d.value.Load()
+}
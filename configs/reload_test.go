@@ -0,0 +1,147 @@
+package configs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// reloadFixture serves application-test.yml from a temporary directory and
+// gives Reload a fresh config in effect and no subscribers.
+type reloadFixture struct {
+	t       *testing.T
+	path    string
+	content string
+	cfg     *Config
+}
+
+func newReloadFixture(t *testing.T) *reloadFixture {
+	t.Helper()
+	original, err := os.ReadFile("application-test.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	f := &reloadFixture{t: t, path: filepath.Join(dir, "app", "configs", "application-test.yml")}
+	if err = os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f.write(string(original))
+
+	wd, _ := os.Getwd()
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_ENV", TEST)
+	previous, previousSubscribers := current.Load(), subscribers
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+		current.Store(previous)
+		subscribers = previousSubscribers
+	})
+
+	subscribers = nil
+	if f.cfg, err = read(); err != nil {
+		t.Fatal(err)
+	}
+	current.Store(f.cfg)
+	return f
+}
+
+func (f *reloadFixture) write(content string) {
+	f.t.Helper()
+	if err := os.WriteFile(f.path, []byte(content), 0o600); err != nil {
+		f.t.Fatal(err)
+	}
+	f.content = content
+}
+
+// change rewrites the file with old replaced by new.
+func (f *reloadFixture) change(old, new string) {
+	f.t.Helper()
+	if !strings.Contains(f.content, old) {
+		f.t.Fatalf("%q not found in application-test.yml", old)
+	}
+	f.write(strings.Replace(f.content, old, new, 1))
+}
+
+func TestReloadRefusesInvalidConfig(t *testing.T) {
+	f := newReloadFixture(t)
+	live := Live(f.cfg)
+	prepared := false
+	Subscribe(func(next *Config) (func(), error) {
+		prepared = true
+		return func() {}, nil
+	})
+
+	f.change("port: 8099", "port: 0")
+	_, err := Reload()
+	var report *ValidationError
+	if !errors.As(err, &report) {
+		t.Fatalf("want the validation report, got %v", err)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Path != "server.port" {
+		t.Errorf("want server.port reported, got %+v", report.Violations)
+	}
+	if prepared {
+		t.Error("want no subscriber asked to prepare a refused config")
+	}
+	if live.Load() != f.cfg || current.Load() != f.cfg {
+		t.Error("want the config in effect kept")
+	}
+}
+
+func TestReloadReportsRestartRequired(t *testing.T) {
+	f := newReloadFixture(t)
+	live := Live(f.cfg)
+
+	f.change("port: 8099", "port: 8100")
+	restartRequired, err := Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(restartRequired, []string{"server.port"}) {
+		t.Errorf("want only server.port to require a restart, got %v", restartRequired)
+	}
+	if live.Load().Server.Port != 8100 || current.Load().Server.Port != 8100 {
+		t.Error("want the new config swapped in")
+	}
+
+	f.change("level: debug", "level: warn")
+	if restartRequired, err = Reload(); err != nil || len(restartRequired) != 0 {
+		t.Errorf("want logging.level applied without a restart, got %v %v", restartRequired, err)
+	}
+	if live.Load().Logging.Level != "warn" {
+		t.Error("want the new level in effect")
+	}
+}
+
+func TestReloadAbortsWhenASubscriberRefuses(t *testing.T) {
+	f := newReloadFixture(t)
+	live := Live(f.cfg)
+	applied := 0
+	Subscribe(func(next *Config) (func(), error) {
+		return func() { applied++ }, nil
+	})
+	refusal := errors.New("policy does not compile")
+	Subscribe(func(next *Config) (func(), error) {
+		return nil, refusal
+	})
+	Subscribe(func(next *Config) (func(), error) {
+		return func() { applied++ }, nil
+	})
+
+	f.change("level: debug", "level: warn")
+	if _, err := Reload(); !errors.Is(err, refusal) {
+		t.Fatalf("want the refusal returned, got %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("want no subscriber applied, %d were", applied)
+	}
+	if live.Load() != f.cfg || current.Load() != f.cfg {
+		t.Error("want the config in effect kept")
+	}
+}
@@ -0,0 +1,184 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// squashed stands for a struct whose fields mapstructure lifts into its
+// parent, so that it does not show up in the reported config path.
+const squashed = "~"
+
+// releaseMode is the gin run mode of production builds.
+const releaseMode = "release"
+
+var (
+	validatorOnce sync.Once
+	validate      *validator.Validate
+	registered    atomic.Pointer[[]Route]
+)
+
+// Violation is one broken rule, reported with the path of the field as it
+// is written in the application yml, e.g. kafka.consumer.groupID.
+type Violation struct {
+	Path  string
+	Rule  string
+	Value interface{}
+}
+
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		lines = append(lines, fmt.Sprintf("  %s: violates %q (value: %v)", violation.Path, violation.Rule, violation.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the rules declared in the validate tags of the config
+// structs and returns all violations at once.
+func Validate(cfg *Config) error {
+	validatorOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+		validate.RegisterTagNameFunc(fieldPath)
+		validate.RegisterStructValidation(validateConfig, Config{})
+		_ = validate.RegisterValidation("expanded", isExpanded)
+	})
+
+	err := validate.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+	report := &ValidationError{}
+	for _, fieldError := range fieldErrors {
+		rule := fieldError.Tag()
+		if fieldError.Param() != "" {
+			rule += "=" + fieldError.Param()
+		}
+		report.Violations = append(report.Violations, Violation{
+			Path:  configPath(fieldError.Namespace()),
+			Rule:  rule,
+			Value: fieldError.Value(),
+		})
+	}
+	return report
+}
+
+// validateConfig checks the rules that span several sections.
+func validateConfig(sl validator.StructLevel) {
+	validateKeySources(sl)
+	validateDevIssuer(sl)
+	validateClientScopes(sl)
+	validateUnscopedRoutes(sl)
+}
+
+// RegisterRoutes hands Validate the routes the server serves, after which
+// every unscopedRoutes entry has to match one of them, and checks cfg
+// against them right away.
+func RegisterRoutes(cfg *Config, routes []Route) error {
+	registered.Store(&routes)
+	return Validate(cfg)
+}
+
+// validateUnscopedRoutes rejects unscopedRoutes entries that match no served
+// route, since such a hole in authorization would only open up silently once
+// a route of that path is added.
+func validateUnscopedRoutes(sl validator.StructLevel) {
+	routes := registered.Load()
+	if routes == nil {
+		return
+	}
+	cfg := sl.Current().Interface().(Config)
+	for i, item := range cfg.UnscopedRoutes {
+		if !slices.ContainsFunc(*routes, func(served Route) bool { return item.Matches(served.Method, served.Path) }) {
+			sl.ReportError(item.Path, fmt.Sprintf("unscopedRoutes[%d]", i), "UnscopedRoutes", "registered_route", item.Method)
+		}
+	}
+}
+
+// validateClientScopes requires a validScopes entry for every client that
+// authenticates with a certificate or an api key, without which authorization
+// refuses all of its requests.
+func validateClientScopes(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+	for i, client := range cfg.Mtls.Clients {
+		if _, exists := cfg.ValidScopes[client.ClientId]; !exists {
+			sl.ReportError(client.ClientId, fmt.Sprintf("mtls.clients[%d].clientId", i), "ClientId", "valid_scopes_defined", "")
+		}
+	}
+	for i, key := range cfg.ApiKeys.Keys {
+		if _, exists := cfg.ValidScopes[key.ClientId]; !exists {
+			sl.ReportError(key.ClientId, fmt.Sprintf("apiKeys.keys[%d].clientId", i), "ClientId", "valid_scopes_defined", "")
+		}
+	}
+}
+
+// validateDevIssuer keeps the development issuer, whose clients and secrets
+// are in the repository, out of release builds.
+func validateDevIssuer(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+	if cfg.DevIssuer.Enabled && cfg.Server.RunMode == releaseMode {
+		sl.ReportError(cfg.DevIssuer.Enabled, "devIssuer.enabled", "Enabled", "disabled_in_release", cfg.Server.RunMode)
+	}
+}
+
+// validateKeySources requires a way to verify the tokens of every JWT issuer,
+// its JWKS or else its own static publicKeys.
+func validateKeySources(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+	for i, issuer := range cfg.TrustedIssuers {
+		if issuer.Mode != "" && issuer.Mode != "jwt" {
+			continue
+		}
+		if issuer.Jwks.Url == "" && issuer.Jwks.FilePath == "" && len(issuer.PublicKeys) == 0 {
+			sl.ReportError(issuer.PublicKeys, fmt.Sprintf("trustedIssuers[%d].publicKeys", i), "PublicKeys", "required_without_jwks", issuer.Issuer)
+		}
+	}
+}
+
+// isExpanded fails a secret still holding a ${VAR} reference, which means
+// the variable was not set in the environment.
+func isExpanded(fl validator.FieldLevel) bool {
+	return !strings.Contains(fl.Field().String(), "${")
+}
+
+// fieldPath names a field the way mapstructure looks it up in the yml.
+func fieldPath(field reflect.StructField) string {
+	tag := field.Tag.Get("mapstructure")
+	name, options, _ := strings.Cut(tag, ",")
+	if field.Anonymous && strings.Contains(options, "squash") {
+		return squashed
+	}
+	if name != "" {
+		return name
+	}
+	runes := []rune(field.Name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func configPath(namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != squashed {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}
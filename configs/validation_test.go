@@ -0,0 +1,193 @@
+package configs
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// validConfig is the smallest config Validate accepts.
+func validConfig() *Config {
+	cfg := &Config{}
+	cfg.Server = Server{Port: 8080, RunMode: "debug"}
+	cfg.Logging = Logging{Level: "info", Logger: "zerolog", Console: true}
+	cfg.Kafka = Kafka{BootstrapServers: "localhost:9092", Topic: "requests", Consumer: Consumer{GroupID: "edge"}}
+	cfg.Idempotency = Idempotency{Ttl: time.Hour, PendingTtl: time.Minute}
+	cfg.Timeout = Timeout{Default: 30 * time.Second, Max: time.Minute}
+	cfg.ValidScopes = map[string]string{"garm_client": "profile"}
+	cfg.TrustedIssuers = []TrustedIssuer{{Issuer: "https://idp.example.com", Jwks: Jwks{Url: "https://idp.example.com/certs"}}}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(cfg *Config)
+		want   map[string]string
+	}{
+		{"valid", func(cfg *Config) {}, nil},
+		{
+			"field of an embedded section",
+			func(cfg *Config) { cfg.Server.Port = 70000 },
+			map[string]string{"server.port": "max=65535"},
+		},
+		{
+			"squashed fields",
+			func(cfg *Config) {
+				cfg.NetworkAccess.Allow = []string{"10.0.0.0/8", "ten"}
+				cfg.NetworkAccess.Routes = []RouteNetworkAccess{{Path: "/api/v1/", NetworkRule: NetworkRule{Deny: []string{"nowhere"}}}}
+			},
+			map[string]string{"networkAccess.allow[1]": "cidr|ip", "networkAccess.routes[0].deny[0]": "cidr|ip"},
+		},
+		{
+			"nested slice field",
+			func(cfg *Config) { cfg.TrustedIssuers[0].Algorithms = []string{"RS256", "HS256"} },
+			map[string]string{"trustedIssuers[0].algorithms[1]": "oneof=RS256 RS384 RS512 PS256 ES256 ES384 EdDSA"},
+		},
+		{
+			"issuer without a key source",
+			func(cfg *Config) {
+				cfg.TrustedIssuers = append(cfg.TrustedIssuers, TrustedIssuer{Issuer: "https://other.example.com"})
+			},
+			map[string]string{"trustedIssuers[1].publicKeys": "required_without_jwks=https://other.example.com"},
+		},
+		{
+			"issuer with static keys only",
+			func(cfg *Config) {
+				cfg.TrustedIssuers[0].Jwks = Jwks{}
+				cfg.TrustedIssuers[0].PublicKeys = map[string]string{"garm_client": "MIIB"}
+			},
+			nil,
+		},
+		{
+			"introspection issuer needs no key source",
+			func(cfg *Config) {
+				cfg.TrustedIssuers[0].Mode = "introspection"
+				cfg.TrustedIssuers[0].Jwks = Jwks{}
+			},
+			nil,
+		},
+		{
+			"secret not expanded",
+			func(cfg *Config) {
+				cfg.IdentityForwarding.Assertion = IdentityAssertion{Enabled: true, Secret: "${IDENTITY_ASSERTION_SECRET}"}
+				cfg.RequestSigning.Clients = []SigningClient{{ClientId: "garm_client", Secret: "${BILLING_SIGNING_SECRET}"}}
+			},
+			map[string]string{
+				"identityForwarding.assertion.secret": "expanded",
+				"requestSigning.clients[0].secret":    "expanded",
+			},
+		},
+		{
+			"dev issuer in release mode",
+			func(cfg *Config) {
+				cfg.Server.RunMode = releaseMode
+				cfg.DevIssuer = DevIssuer{Enabled: true, Issuer: "http://localhost:8080/issuer"}
+			},
+			map[string]string{"devIssuer.enabled": "disabled_in_release=release"},
+		},
+		{
+			"dev issuer in debug mode",
+			func(cfg *Config) { cfg.DevIssuer = DevIssuer{Enabled: true, Issuer: "http://localhost:8080/issuer"} },
+			nil,
+		},
+		{
+			"clients without valid scopes",
+			func(cfg *Config) {
+				cfg.Mtls.Clients = []CertificateClient{{San: "spiffe://internal/reporting", ClientId: "reporting_service"}}
+				cfg.ApiKeys.Keys = []ApiKey{{Id: "k1", Hash: "sha256:00", ClientId: "garm_client"}, {Id: "k2", Hash: "sha256:00", ClientId: "legacy_partner"}}
+			},
+			map[string]string{
+				"mtls.clients[0].clientId": "valid_scopes_defined",
+				"apiKeys.keys[1].clientId": "valid_scopes_defined",
+			},
+		},
+		{
+			"route timeout below its default",
+			func(cfg *Config) {
+				cfg.Timeout.Routes = []RouteTimeout{
+					{Path: "/api/v1/", Default: time.Minute, Max: time.Second},
+					{Path: "/api/v1/reports", Default: time.Minute},
+				}
+			},
+			map[string]string{"timeout.routes[0].max": "gtefield=Default"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := validConfig()
+			test.mutate(cfg)
+			err := Validate(cfg)
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("want the config accepted, got\n%v", err)
+				}
+				return
+			}
+			var report *ValidationError
+			if !errors.As(err, &report) {
+				t.Fatalf("want a ValidationError, got %v", err)
+			}
+			got := map[string]string{}
+			for _, violation := range report.Violations {
+				got[violation.Path] = violation.Rule
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("want %v, got %v", sorted(test.want), sorted(got))
+			}
+		})
+	}
+}
+
+func sorted(violations map[string]string) []string {
+	lines := make([]string, 0, len(violations))
+	for path, rule := range violations {
+		lines = append(lines, path+": "+rule)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	t.Cleanup(func() { registered.Store(nil) })
+	routes := []Route{{Method: "GET", Path: "/api/v1/"}, {Method: "GET", Path: "/api/v1/topics/:topic"}, {Method: "GET", Path: "/api/v1/health"}}
+
+	tests := []struct {
+		name     string
+		unscoped []Route
+		want     []string
+	}{
+		{"none", nil, nil},
+		{"served route", []Route{{Method: "GET", Path: "/api/v1/topics/:topic"}}, nil},
+		{"any method", []Route{{Method: "*", Path: "/api/v1/health"}}, nil},
+		{"prefix", []Route{{Path: "/api/v1/topics/*"}}, nil},
+		{"route not served", []Route{{Method: "GET", Path: "/api/v1/whoami"}}, []string{"unscopedRoutes[0]"}},
+		{"method not served", []Route{{Method: "GET", Path: "/api/v1/"}, {Method: "DELETE", Path: "/api/v1/"}}, []string{"unscopedRoutes[1]"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.UnscopedRoutes = test.unscoped
+			err := RegisterRoutes(cfg, routes)
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("want the config accepted, got\n%v", err)
+				}
+				return
+			}
+			var report *ValidationError
+			if !errors.As(err, &report) {
+				t.Fatalf("want a ValidationError, got %v", err)
+			}
+			var got []string
+			for _, violation := range report.Violations {
+				got = append(got, violation.Path)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("want %v, got %v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+	"edge-app/pkg/route"
+	goerrors "errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	StageAuthentication = "authentication"
+	StageAuthorization  = "authorization"
+	StageSignature      = "signature"
+	StageNetwork        = "network"
+)
+
+var (
+	once    sync.Once
+	auditor *Auditor
+)
+
+// Event records one access decision of the edge. Reason holds the pkg/errors
+// description of a denial and is empty when access was granted.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Stage    string    `json:"stage"`
+	Decision string    `json:"decision"`
+	ClientId string    `json:"clientId"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	AuthMode string    `json:"authMode"`
+	Method   string    `json:"method"`
+	Route    string    `json:"route"`
+	Reason   string    `json:"reason"`
+	Ip       string    `json:"ip"`
+	TraceId  string    `json:"traceId"`
+}
+
+type Sink interface {
+	Write(event Event) error
+}
+
+type Auditor struct {
+	logger logging.Logger
+	sinks  []Sink
+}
+
+// NewAuditor returns the process wide auditor, so that every middleware
+// shares one audit file and one Kafka producer.
+func NewAuditor(cfg *configs.Config) *Auditor {
+	once.Do(func() {
+		auditor = &Auditor{logger: logging.NewLogger(cfg)}
+		if !cfg.Audit.Enabled {
+			return
+		}
+		sink, err := newLogSink(cfg.Audit.FilePath)
+		if err != nil {
+			panic(err)
+		}
+		auditor.sinks = append(auditor.sinks, sink)
+		if cfg.Audit.Kafka.Enabled {
+			sink, err := newKafkaSink(cfg, auditor.logger)
+			if err != nil {
+				panic(err)
+			}
+			auditor.sinks = append(auditor.sinks, sink)
+		}
+	})
+	return auditor
+}
+
+// Record writes the decision taken for the request to every sink. A nil err
+// means access was granted.
+func (a *Auditor) Record(ctx *gin.Context, stage string, decision string, err error) {
+	if len(a.sinks) == 0 {
+		return
+	}
+	event := Event{
+		Time:     time.Now().UTC(),
+		Stage:    stage,
+		Decision: decision,
+		ClientId: ctx.GetString(constant.Aud),
+		Subject:  ctx.GetString(constant.Sub),
+		Issuer:   ctx.GetString(constant.Iss),
+		AuthMode: ctx.GetString(constant.AuthMode),
+		Method:   ctx.Request.Method,
+		Route:    route.Path(ctx),
+		Reason:   reason(err),
+		Ip:       ctx.ClientIP(),
+		TraceId:  traceId(ctx),
+	}
+	for _, sink := range a.sinks {
+		if err := sink.Write(event); err != nil {
+			a.logger.Error(logging.Auth, logging.Audit, err.Error(), nil)
+		}
+	}
+}
+
+func reason(err error) string {
+	if err == nil {
+		return ""
+	}
+	var serviceError *errors.ServiceError
+	if goerrors.As(err, &serviceError) {
+		return serviceError.ErrorDescription
+	}
+	return err.Error()
+}
+
+// traceId reads the span of the request, falling back to the incoming trace
+// context because the decisions are taken before the tracing middleware runs.
+func traceId(ctx *gin.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx.Request.Context())
+	if !spanContext.IsValid() {
+		carrier := propagation.HeaderCarrier(ctx.Request.Header)
+		spanContext = trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(ctx.Request.Context(), carrier))
+	}
+	if !spanContext.IsValid() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
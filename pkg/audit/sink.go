@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/logging"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/rs/zerolog"
+)
+
+// logSink writes one JSON line per event to a file of its own, or to stdout,
+// apart from the application log and its level.
+type logSink struct {
+	logger zerolog.Logger
+}
+
+func newLogSink(filePath string) (*logSink, error) {
+	var writer io.Writer = os.Stdout
+	if filePath != "" {
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o666)
+		if err != nil {
+			return nil, err
+		}
+		writer = file
+	}
+	return &logSink{logger: zerolog.New(writer)}, nil
+}
+
+func (s *logSink) Write(event Event) error {
+	s.logger.Log().
+		Time("time", event.Time).
+		Str("stage", event.Stage).
+		Str("decision", event.Decision).
+		Str("clientId", event.ClientId).
+		Str("subject", event.Subject).
+		Str("issuer", event.Issuer).
+		Str("authMode", event.AuthMode).
+		Str("method", event.Method).
+		Str("route", event.Route).
+		Str("reason", event.Reason).
+		Str("ip", event.Ip).
+		Str("traceId", event.TraceId).
+		Send()
+	return nil
+}
+
+// kafkaSink publishes events as JSON keyed by client id. Delivery is
+// asynchronous so that auditing never holds up the request.
+type kafkaSink struct {
+	topic    string
+	producer *kafka.Producer
+}
+
+func newKafkaSink(cfg *configs.Config, logger logging.Logger) (*kafkaSink, error) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": cfg.Kafka.BootstrapServers,
+		"security.protocol": cfg.Kafka.SecurityProtocol,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for e := range producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				logger.Error(logging.Kafka, logging.Audit, m.TopicPartition.Error.Error(), nil)
+			}
+		}
+	}()
+	return &kafkaSink{topic: cfg.Audit.Kafka.Topic, producer: producer}, nil
+}
+
+func (s *kafkaSink) Write(event Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &s.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(event.ClientId),
+		Value:          value,
+	}, nil)
+}
@@ -0,0 +1,93 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"edge-app/pkg/errors"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AlgNone  = "none"
+	AlgEdDSA = "EdDSA"
+)
+
+var signingMethods = map[string]jwt.SigningMethod{
+	jwt.SigningMethodRS256.Alg(): jwt.SigningMethodRS256,
+	jwt.SigningMethodRS384.Alg(): jwt.SigningMethodRS384,
+	jwt.SigningMethodRS512.Alg(): jwt.SigningMethodRS512,
+	jwt.SigningMethodPS256.Alg(): jwt.SigningMethodPS256,
+	jwt.SigningMethodES256.Alg(): jwt.SigningMethodES256,
+	jwt.SigningMethodES384.Alg(): jwt.SigningMethodES384,
+	AlgEdDSA:                     signingMethodEdDSA,
+}
+
+// signingMethod resolves the algorithm named in the token header. It refuses
+// "none" and HMAC outright, since a public key must never be used as an HMAC
+// secret, and checks the algorithm against both the allow-list and the key.
+func signingMethod(alg string, allowed []string, key any) (jwt.SigningMethod, error) {
+	if alg == "" || strings.EqualFold(alg, AlgNone) || strings.HasPrefix(strings.ToUpper(alg), "HS") {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrAlgorithmNotAllowed, OriginalValue: alg}
+	}
+	method, supported := signingMethods[alg]
+	if !supported || !slices.Contains(allowed, alg) {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrAlgorithmNotAllowed, OriginalValue: alg}
+	}
+	if !keyMatchesAlgorithm(alg, key) {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrAlgorithmKeyMismatch, OriginalValue: alg}
+	}
+	return method, nil
+}
+
+func keyMatchesAlgorithm(alg string, key any) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		switch alg {
+		case jwt.SigningMethodES256.Alg():
+			return k.Curve == elliptic.P256()
+		case jwt.SigningMethodES384.Alg():
+			return k.Curve == elliptic.P384()
+		}
+	case ed25519.PublicKey:
+		return alg == AlgEdDSA
+	}
+	return false
+}
+
+type eddsaSigningMethod struct{}
+
+var signingMethodEdDSA = &eddsaSigningMethod{}
+
+func (m *eddsaSigningMethod) Alg() string {
+	return AlgEdDSA
+}
+
+func (m *eddsaSigningMethod) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *eddsaSigningMethod) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
@@ -0,0 +1,175 @@
+package authentication
+
+import (
+	"crypto/sha256"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+	"edge-app/pkg/revocation"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	ModeApiKey   = "apikey"
+	hashPrefix   = "sha256:"
+	claimEnabled = "enabled"
+	claimKeyId   = "jti"
+)
+
+type apiKey struct {
+	configs.ApiKey
+	notBefore time.Time
+	expiresAt time.Time
+}
+
+// ApiKeys indexes the configured keys by the sha256 of the secret, so the
+// plain keys never have to be stored. Rotation is done by registering the new
+// key with a validity overlapping the old one.
+type ApiKeys struct {
+	header string
+	byHash map[string]*apiKey
+}
+
+func NewApiKeys(cfg *configs.Config) (*ApiKeys, error) {
+	keys := cfg.ApiKeys.Keys
+	if cfg.ApiKeys.FilePath != "" {
+		data, err := os.ReadFile(cfg.ApiKeys.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		var fileKeys []configs.ApiKey
+		if err = json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("api key file %s: %w", cfg.ApiKeys.FilePath, err)
+		}
+		// Keys of the config are checked by configs.Validate, these only here.
+		for _, item := range fileKeys {
+			if _, exists := cfg.ValidScopes[item.ClientId]; !exists {
+				return nil, fmt.Errorf("api key file %s: key %s: client %s has no validScopes entry", cfg.ApiKeys.FilePath, item.Id, item.ClientId)
+			}
+		}
+		keys = append(keys, fileKeys...)
+	}
+
+	apiKeys := &ApiKeys{header: cfg.ApiKeys.Header, byHash: map[string]*apiKey{}}
+	if apiKeys.header == "" {
+		apiKeys.header = constant.ApiKey
+	}
+	for _, item := range keys {
+		key := &apiKey{ApiKey: item}
+		var err error
+		if item.NotBefore != "" {
+			if key.notBefore, err = time.Parse(time.RFC3339, item.NotBefore); err != nil {
+				return nil, fmt.Errorf("api key %s: notBefore: %w", item.Id, err)
+			}
+		}
+		if item.ExpiresAt != "" {
+			if key.expiresAt, err = time.Parse(time.RFC3339, item.ExpiresAt); err != nil {
+				return nil, fmt.Errorf("api key %s: expiresAt: %w", item.Id, err)
+			}
+		}
+		apiKeys.byHash[strings.ToLower(strings.TrimPrefix(item.Hash, hashPrefix))] = key
+	}
+	return apiKeys, nil
+}
+
+func (a *ApiKeys) Header() string {
+	return a.header
+}
+
+type ApiKeyService struct {
+	logger  logging.Logger
+	cfg     *configs.Config
+	keys    *ApiKeys
+	revoked *revocation.List
+	Tpl
+}
+
+func NewApiKeyService(cfg *configs.Config, keys *ApiKeys, revoked *revocation.List) *ApiKeyService {
+	logger := logging.NewLogger(cfg)
+	return &ApiKeyService{
+		cfg:     cfg,
+		logger:  logger,
+		keys:    keys,
+		revoked: revoked,
+	}
+}
+
+func (s *ApiKeyService) extractToken(value string) (token string, err error) {
+	if value = strings.TrimSpace(value); value == "" {
+		return "", &errors.ServiceError{ErrorDescription: errors.ErrApiKeyMissing}
+	}
+	return value, nil
+}
+
+func (s *ApiKeyService) getClaims(token string) (claimMap map[string]interface{}, err error) {
+	hash := sha256.Sum256([]byte(token))
+	key, exists := s.keys.byHash[hex.EncodeToString(hash[:])]
+	if !exists {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrApiKeyInvalid}
+	}
+
+	claimMap = map[string]interface{}{
+		constant.ClientId: key.ClientId,
+		constant.Scope:    strings.Join(key.Scopes, " "),
+		claimKeyId:        key.Id,
+		claimEnabled:      key.Enabled,
+	}
+	if !key.notBefore.IsZero() {
+		claimMap[constant.Nbf] = float64(key.notBefore.Unix())
+	}
+	if !key.expiresAt.IsZero() {
+		claimMap[constant.Exp] = float64(key.expiresAt.Unix())
+	}
+	return claimMap, nil
+}
+
+func (s *ApiKeyService) isIssuerValid(claims map[string]interface{}) (bool, error) {
+	if enabled, _ := claims[claimEnabled].(bool); !enabled {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrApiKeyDisabled}
+	}
+	return true, nil
+}
+
+func (s *ApiKeyService) isSignatureValid(_ map[string]interface{}, _ string) (bool, error) {
+	return true, nil
+}
+
+// isNotRevoked lets a leaked key be revoked by its id as jti.
+func (s *ApiKeyService) isNotRevoked(claims map[string]interface{}) (bool, error) {
+	keyId, _ := claims[claimKeyId].(string)
+	clientId, _ := claims[constant.ClientId].(string)
+	if err := s.revoked.Check(keyId, "", clientId); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *ApiKeyService) isLifetimeValid(claims map[string]interface{}) (bool, error) {
+	return checkLifetime(claims, 0, false)
+}
+
+func (s *ApiKeyService) isAudienceValid(_ map[string]interface{}) (bool, error) {
+	return true, nil
+}
+
+func (s *ApiKeyService) getPrincipal(claims map[string]interface{}) (*Principal, error) {
+	clientId, _ := claims[constant.ClientId].(string)
+	if clientId == "" {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrClientIdNotFound}
+	}
+	scope, _ := claims[constant.Scope].(string)
+	keyId, _ := claims[claimKeyId].(string)
+	return &Principal{
+		ClientId: clientId,
+		Subject:  keyId,
+		Scope:    scope,
+		Issuer:   ModeApiKey,
+		Claims:   claims,
+	}, nil
+}
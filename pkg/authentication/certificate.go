@@ -0,0 +1,135 @@
+package authentication
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+	"edge-app/pkg/revocation"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ModeCertificate = "mtls"
+	claimCommonName = "cn"
+	claimSan        = "san"
+)
+
+// CertificateService authenticates callers by the client certificate the TLS
+// handshake has already verified against the configured CA, and maps its
+// subject or SAN to a client registered in config.
+type CertificateService struct {
+	logger  logging.Logger
+	cfg     *configs.Config
+	revoked *revocation.List
+}
+
+// certificateVerification runs the template for the TLS state of a single
+// connection on top of the shared service.
+type certificateVerification struct {
+	*CertificateService
+	state *tls.ConnectionState
+}
+
+func NewCertificateService(cfg *configs.Config, revoked *revocation.List) *CertificateService {
+	logger := logging.NewLogger(cfg)
+	return &CertificateService{
+		cfg:     cfg,
+		logger:  logger,
+		revoked: revoked,
+	}
+}
+
+func (s *CertificateService) Verify(state *tls.ConnectionState) (*Principal, error) {
+	template := Tpl{Impl: &certificateVerification{CertificateService: s, state: state}}
+	return template.VerifyTokenTP("")
+}
+
+func HasCertificate(state *tls.ConnectionState) bool {
+	return state != nil && len(state.PeerCertificates) > 0
+}
+
+func (s *certificateVerification) certificate() *x509.Certificate {
+	return s.state.PeerCertificates[0]
+}
+
+func (s *certificateVerification) extractToken(_ string) (token string, err error) {
+	if !HasCertificate(s.state) {
+		return "", &errors.ServiceError{ErrorDescription: errors.ErrCertificateMissing}
+	}
+	return "", nil
+}
+
+func (s *certificateVerification) getClaims(_ string) (claimMap map[string]interface{}, err error) {
+	cert := s.certificate()
+	var sans []interface{}
+	for _, name := range cert.DNSNames {
+		sans = append(sans, name)
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		sans = append(sans, email)
+	}
+	return map[string]interface{}{
+		constant.Sub:    cert.Subject.String(),
+		claimCommonName: cert.Subject.CommonName,
+		claimSan:        sans,
+	}, nil
+}
+
+func (s *certificateVerification) isIssuerValid(_ map[string]interface{}) (bool, error) {
+	if len(s.state.VerifiedChains) == 0 {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrCertificateInvalid}
+	}
+	return true, nil
+}
+
+func (s *certificateVerification) isSignatureValid(_ map[string]interface{}, _ string) (bool, error) {
+	return true, nil
+}
+
+// isNotRevoked checks the subject of the certificate, the client it maps to
+// is only known once the principal is resolved.
+func (s *certificateVerification) isNotRevoked(claims map[string]interface{}) (bool, error) {
+	subject, _ := claims[constant.Sub].(string)
+	if err := s.revoked.Check("", subject, ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *certificateVerification) isLifetimeValid(_ map[string]interface{}) (bool, error) {
+	cert := s.certificate()
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrCertificateInvalid}
+	}
+	return true, nil
+}
+
+func (s *certificateVerification) isAudienceValid(_ map[string]interface{}) (bool, error) {
+	return true, nil
+}
+
+func (s *certificateVerification) getPrincipal(claims map[string]interface{}) (*Principal, error) {
+	subject, _ := claims[constant.Sub].(string)
+	sans := stringValues(claims[claimSan])
+	for _, client := range s.cfg.Mtls.Clients {
+		if (client.Subject != "" && client.Subject == subject) || (client.San != "" && slices.Contains(sans, client.San)) {
+			return &Principal{
+				ClientId: client.ClientId,
+				Subject:  subject,
+				Scope:    strings.Join(client.Scopes, " "),
+				Issuer:   s.certificate().Issuer.String(),
+				Claims:   claims,
+			}, nil
+		}
+	}
+	return nil, &errors.ServiceError{ErrorDescription: errors.ErrCertificateUnknown, OriginalValue: subject}
+}
@@ -0,0 +1,140 @@
+package authentication
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/cache"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/keyset"
+	"edge-app/pkg/logging"
+	"edge-app/pkg/route"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	ModeJwt           = "jwt"
+	ModeIntrospection = "introspection"
+)
+
+type TrustedIssuer struct {
+	configs.TrustedIssuer
+	keys       keyset.Provider
+	client     *http.Client
+	introspect *cache.LRU[map[string]interface{}]
+}
+
+// Credentials summarises what the caller presented on the request.
+type Credentials struct {
+	Authorization string
+	ApiKey        string
+	Certificate   bool
+}
+
+type Issuers struct {
+	byIssuer map[string]*TrustedIssuer
+	ordered  []*TrustedIssuer
+}
+
+// NewIssuers indexes the trusted issuers by their exact iss value, each with
+// its own key source and the claim mapping defaults filled in.
+func NewIssuers(cfg *configs.Config) *Issuers {
+	logger := logging.NewLogger(cfg)
+	issuers := &Issuers{byIssuer: map[string]*TrustedIssuer{}}
+	for _, item := range cfg.TrustedIssuers {
+		if item.Mode == "" {
+			item.Mode = ModeJwt
+		}
+		if len(item.Algorithms) == 0 {
+			item.Algorithms = []string{jwt.SigningMethodRS256.Alg()}
+		}
+		if item.ClaimMappings.ClientId == "" {
+			item.ClaimMappings.ClientId = constant.Aud
+		}
+		if item.ClaimMappings.Scope == "" {
+			item.ClaimMappings.Scope = constant.Scope
+		}
+		if item.ClaimMappings.Subject == "" {
+			item.ClaimMappings.Subject = constant.Sub
+		}
+		if len(item.ClaimMappings.Roles) == 0 {
+			item.ClaimMappings.Roles = []string{constant.Roles}
+		}
+		if len(item.ClaimMappings.Groups) == 0 {
+			item.ClaimMappings.Groups = []string{constant.Groups}
+		}
+		issuer := &TrustedIssuer{
+			TrustedIssuer: item,
+			keys:          keyset.NewProvider(item.Jwks, item.PublicKeys, logger),
+		}
+		if item.Introspection.Url != "" {
+			issuer.client = &http.Client{Timeout: item.Introspection.Timeout}
+			issuer.introspect = cache.NewLRU[map[string]interface{}](item.Introspection.CacheMaxEntries)
+		}
+		issuers.byIssuer[item.Issuer] = issuer
+		issuers.ordered = append(issuers.ordered, issuer)
+	}
+	return issuers
+}
+
+func (i *Issuers) All() []*TrustedIssuer {
+	return i.ordered
+}
+
+func (i *Issuers) Get(iss string) (*TrustedIssuer, bool) {
+	issuer, exists := i.byIssuer[iss]
+	return issuer, exists
+}
+
+// Resolve decides how the caller of a request is verified. A matching route
+// rule wins. Without a bearer token an API key and then a verified client
+// certificate are used, otherwise the mode of the issuer named by a JWT.
+// Opaque tokens go to the first issuer that offers introspection.
+func (i *Issuers) Resolve(routes []configs.AuthenticationRoute, method, path string, credentials Credentials) (string, *TrustedIssuer) {
+	for _, item := range routes {
+		if !route.Match(item.Method, item.Path, method, path) {
+			continue
+		}
+		if item.Mode != ModeIntrospection {
+			return item.Mode, nil
+		}
+		if issuer, exists := i.byIssuer[item.Issuer]; exists {
+			return ModeIntrospection, issuer
+		}
+		return ModeIntrospection, i.firstIntrospection()
+	}
+
+	token, err := bearerToken(credentials.Authorization)
+	if err != nil {
+		if credentials.ApiKey != "" {
+			return ModeApiKey, nil
+		}
+		if credentials.Certificate {
+			return ModeCertificate, nil
+		}
+		return ModeJwt, nil
+	}
+	if strings.Count(token, ".") == 2 {
+		if claims, err := decodeClaims(token); err == nil {
+			iss, _ := claims[constant.Iss].(string)
+			if issuer, exists := i.byIssuer[iss]; exists && issuer.Mode == ModeIntrospection {
+				return ModeIntrospection, issuer
+			}
+		}
+		return ModeJwt, nil
+	}
+	if issuer := i.firstIntrospection(); issuer != nil {
+		return ModeIntrospection, issuer
+	}
+	return ModeJwt, nil
+}
+
+func (i *Issuers) firstIntrospection() *TrustedIssuer {
+	for _, issuer := range i.ordered {
+		if issuer.introspect != nil {
+			return issuer
+		}
+	}
+	return nil
+}
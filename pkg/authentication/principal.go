@@ -0,0 +1,13 @@
+package authentication
+
+// Principal is the verified identity of the caller, independent of how it
+// was authenticated.
+type Principal struct {
+	ClientId string
+	Subject  string
+	Scope    string
+	Roles    []string
+	Groups   []string
+	Issuer   string
+	Claims   map[string]interface{}
+}
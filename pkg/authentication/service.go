@@ -1,92 +1,377 @@
 package authentication
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"edge-app/configs"
+	"edge-app/pkg/cache"
 	"edge-app/pkg/constant"
 	"edge-app/pkg/errors"
 	"edge-app/pkg/logging"
+	"edge-app/pkg/metrics"
+	"edge-app/pkg/revocation"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
 type Service struct {
-	logger logging.Logger
-	cfg    *configs.Config
+	logger   logging.Logger
+	cfg      *configs.Config
+	issuers  *Issuers
+	verified *cache.LRU[map[string]interface{}]
+	revoked  *revocation.List
 	Tpl
 }
 
-func NewAuthenticationService(cfg *configs.Config) *Service {
+// NewAuthenticationService is meant to be built once and shared by every
+// request, so that the verified token cache is too.
+func NewAuthenticationService(cfg *configs.Config, issuers *Issuers, revoked *revocation.List) *Service {
 	logger := logging.NewLogger(cfg)
-	return &Service{
-		cfg:    cfg,
-		logger: logger,
+	service := &Service{
+		cfg:     cfg,
+		logger:  logger,
+		issuers: issuers,
+		revoked: revoked,
 	}
+	if cfg.TokenCache.Enabled {
+		service.verified = cache.NewLRU[map[string]interface{}](cfg.TokenCache.MaxEntries)
+	}
+	return service
 }
 
 func (s *Service) extractToken(jwtToken string) (token string, err error) {
-	tokens := strings.Split(jwtToken, " ")
-	if jwtToken == "" || len(tokens) < 2 {
+	return bearerToken(jwtToken)
+}
+
+func bearerToken(authorization string) (token string, err error) {
+	tokens := strings.Split(authorization, " ")
+	if authorization == "" || len(tokens) < 2 {
 		return "", &errors.ServiceError{ErrorDescription: errors.ErrMissingJwtToken}
 	}
 	return tokens[1], nil
 }
 
+// getClaims only decodes the payload. Nothing in it is trusted until the
+// signature has been verified against the key of the claimed issuer.
 func (s *Service) getClaims(token string) (claimMap map[string]interface{}, err error) {
-	claimMap = map[string]interface{}{}
-	accessToken, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if claimMap, found := s.verifiedClaims(token); found {
+		return claimMap, nil
+	}
+	return decodeClaims(token)
+}
+
+func decodeClaims(token string) (claimMap map[string]interface{}, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
+	}
+	data, err := jwt.DecodeSegment(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
 	}
-	if claims, ok := accessToken.Claims.(jwt.MapClaims); ok {
-		for k, v := range claims {
-			claimMap[k] = v
-		}
-		return claimMap, nil
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claimMap); err != nil {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
+	}
+	if claimMap == nil {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrClaimNotFound}
 	}
-	return nil, &errors.ServiceError{ErrorDescription: errors.ErrClaimNotFound}
+	return claimMap, nil
 }
 
 func (s *Service) isSignatureValid(claimMap map[string]interface{}, token string) (bool, error) {
-	clientId, exists := claimMap[constant.Aud]
-	if !exists {
-		return false, &errors.ServiceError{ErrorDescription: errors.ErrClientIdNotFound}
+	if _, found := s.verifiedClaims(token); found {
+		metrics.AuthCache.WithLabelValues("token", "hit").Inc()
+		return true, nil
 	}
+	metrics.AuthCache.WithLabelValues("token", "miss").Inc()
 
-	publicKey, exist := s.cfg.PublicKeys[clientId.(string)]
-	if !exist {
-		return false, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyNotFound}
+	issuer, err := s.trustedIssuer(claimMap)
+	if err != nil {
+		return false, err
 	}
-	pem := constant.BeginPublicKey + publicKey + constant.EndPublicKey
-	key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+	clientId, err := clientIdOf(claimMap, issuer)
 	if err != nil {
-		return false, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyIsInvalid}
+		return false, err
 	}
 
 	parts := strings.Split(token, ".")
-	err = jwt.SigningMethodRS256.Verify(strings.Join(parts[0:2], "."), parts[2], key)
+	if len(parts) != 3 {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
+	}
+	header, err := decodeHeader(parts[0])
+	if err != nil {
+		return false, err
+	}
+
+	key, err := issuer.keys.Key(header.Kid, clientId)
+	if err != nil {
+		return false, err
+	}
+
+	method, err := signingMethod(header.Alg, issuer.Algorithms, key)
+	if err != nil {
+		return false, err
+	}
+
+	err = method.Verify(strings.Join(parts[0:2], "."), parts[2], key)
 	if err != nil {
 		return false, &errors.ServiceError{ErrorDescription: errors.ErrSignatureIsInvalid}
 	}
 
+	s.rememberVerified(claimMap, token)
 	return true, nil
 }
 
-func (s *Service) isExpirationTimeValid(claims map[string]interface{}) (bool, error) {
-	if expireAt, exists := claims[constant.Exp]; exists {
-		if expireAt.(float64) != 0 && expireAt.(float64) < (float64(time.Now().Unix())) {
-			return false, &errors.ServiceError{ErrorDescription: errors.ErrTokenExpired}
-		}
+func (s *Service) verifiedClaims(token string) (map[string]interface{}, bool) {
+	if s.verified == nil {
+		return nil, false
+	}
+	return s.verified.Get(tokenHash(token))
+}
+
+// rememberVerified caches the claims of a token whose signature checked out.
+// Lifetime and audience are still validated on every request, the cache only
+// saves decoding and the signature verification.
+func (s *Service) rememberVerified(claimMap map[string]interface{}, token string) {
+	if s.verified == nil {
+		return
+	}
+	ttl := s.cfg.TokenCache.MaxTtl
+	if expireAt, exists, err := numericDate(claimMap, constant.Exp); err == nil && exists {
+		ttl = min(ttl, time.Until(expireAt))
+	}
+	s.verified.Set(tokenHash(token), claimMap, ttl)
+}
+
+// tokenHash keys caches by token without keeping the bearer credential itself.
+func tokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *Service) isNotRevoked(claims map[string]interface{}) (bool, error) {
+	issuer, err := s.trustedIssuer(claims)
+	if err != nil {
+		return false, err
+	}
+	jti, _ := claims[constant.Jti].(string)
+	subject, _ := claims[issuer.ClaimMappings.Subject].(string)
+	clientId, _ := clientIdOf(claims, issuer)
+	if err = s.revoked.Check(jti, subject, clientId); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func decodeHeader(segment string) (header tokenHeader, err error) {
+	data, err := jwt.DecodeSegment(segment)
+	if err != nil {
+		return header, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
+	}
+	if err = json.Unmarshal(data, &header); err != nil {
+		return header, &errors.ServiceError{ErrorDescription: errors.ErrTokenInvalid}
+	}
+	return header, nil
+}
+
+func (s *Service) isLifetimeValid(claims map[string]interface{}) (bool, error) {
+	issuer, err := s.trustedIssuer(claims)
+	if err != nil {
+		return false, err
+	}
+	return checkLifetime(claims, issuer.Leeway, true)
+}
+
+func checkLifetime(claims map[string]interface{}, leeway time.Duration, requireExp bool) (bool, error) {
+	now := time.Now()
+
+	expireAt, exists, err := numericDate(claims, constant.Exp)
+	if err != nil {
+		return false, err
+	}
+	if !exists && requireExp {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrClaimNotFound, ReferenceName: constant.Exp}
+	}
+	if exists && now.After(expireAt.Add(leeway)) {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrTokenExpired}
+	}
+
+	notBefore, exists, err := numericDate(claims, constant.Nbf)
+	if err != nil {
+		return false, err
+	}
+	if exists && now.Add(leeway).Before(notBefore) {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrTokenNotYetValid}
+	}
+
+	issuedAt, exists, err := numericDate(claims, constant.Iat)
+	if err != nil {
+		return false, err
+	}
+	if exists && now.Add(leeway).Before(issuedAt) {
+		return false, &errors.ServiceError{ErrorDescription: errors.ErrTokenIssuedInFuture}
 	}
 	return true, nil
 }
 
 func (s *Service) isIssuerValid(claims map[string]interface{}) (bool, error) {
-	if iss, exists := claims[constant.Iss]; exists {
-		if strings.Contains(iss.(string), constant.Issuer) {
+	if _, err := s.trustedIssuer(claims); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Service) isAudienceValid(claims map[string]interface{}) (bool, error) {
+	issuer, err := s.trustedIssuer(claims)
+	if err != nil {
+		return false, err
+	}
+	return checkAudience(claims, issuer.Audiences)
+}
+
+func checkAudience(claims map[string]interface{}, allowed []string) (bool, error) {
+	if len(allowed) == 0 {
+		return true, nil
+	}
+	for _, aud := range audiences(claims) {
+		if slices.Contains(allowed, aud) {
 			return true, nil
 		}
 	}
-	return false, &errors.ServiceError{ErrorDescription: errors.ErrIssuerIsInvalid}
+	return false, &errors.ServiceError{ErrorDescription: errors.ErrAudienceIsInvalid}
+}
+
+func (s *Service) getPrincipal(claims map[string]interface{}) (*Principal, error) {
+	issuer, err := s.trustedIssuer(claims)
+	if err != nil {
+		return nil, err
+	}
+	clientId, err := clientIdOf(claims, issuer)
+	if err != nil {
+		return nil, err
+	}
+	subject, _ := claims[issuer.ClaimMappings.Subject].(string)
+	return &Principal{
+		ClientId: clientId,
+		Subject:  subject,
+		Scope:    strings.Join(stringValues(claims[issuer.ClaimMappings.Scope]), " "),
+		Roles:    mappedValues(claims, issuer.ClaimMappings.Roles, clientId),
+		Groups:   mappedValues(claims, issuer.ClaimMappings.Groups, clientId),
+		Issuer:   issuer.Issuer,
+		Claims:   claims,
+	}, nil
+}
+
+// trustedIssuer selects the issuer configuration by an exact match on iss.
+func (s *Service) trustedIssuer(claims map[string]interface{}) (*TrustedIssuer, error) {
+	iss, _ := claims[constant.Iss].(string)
+	if issuer, exists := s.issuers.Get(iss); exists {
+		return issuer, nil
+	}
+	return nil, &errors.ServiceError{ErrorDescription: errors.ErrIssuerIsInvalid, OriginalValue: iss}
+}
+
+// audiences reads aud, which may be a single string or an array of strings.
+func audiences(claims map[string]interface{}) []string {
+	return stringValues(claims[constant.Aud])
+}
+
+// clientIdOf resolves the client identifier through the issuer claim mapping.
+// When it is aud and the token carries several audiences, the first one the
+// issuer accepts wins.
+func clientIdOf(claims map[string]interface{}, issuer *TrustedIssuer) (string, error) {
+	values := stringValues(claims[issuer.ClaimMappings.ClientId])
+	if issuer.ClaimMappings.ClientId == constant.Aud {
+		for _, value := range values {
+			if slices.Contains(issuer.Audiences, value) {
+				return value, nil
+			}
+		}
+	}
+	if len(values) == 0 || values[0] == "" {
+		return "", &errors.ServiceError{ErrorDescription: errors.ErrClientIdNotFound, ReferenceName: issuer.ClaimMappings.ClientId}
+	}
+	return values[0], nil
+}
+
+// mappedValues collects the distinct strings found under any of the claim
+// paths, e.g. realm_access.roles and resource_access.{client}.roles of a
+// Keycloak token.
+func mappedValues(claims map[string]interface{}, paths []string, clientId string) []string {
+	var values []string
+	for _, path := range paths {
+		for _, value := range stringValues(claimAt(claims, path, clientId)) {
+			if !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func claimAt(claims map[string]interface{}, path string, clientId string) interface{} {
+	var current interface{} = claims
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "{client}" {
+			segment = clientId
+		}
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[segment]
+	}
+	return current
+}
+
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func numericDate(claims map[string]interface{}, name string) (time.Time, bool, error) {
+	value, exists := claims[name]
+	if !exists {
+		return time.Time{}, false, nil
+	}
+	var seconds float64
+	switch v := value.(type) {
+	case json.Number:
+		var err error
+		if seconds, err = v.Float64(); err != nil {
+			return time.Time{}, true, &errors.ServiceError{ErrorDescription: errors.ErrClaimIsMalformed, ReferenceName: name}
+		}
+	case float64:
+		seconds = v
+	default:
+		return time.Time{}, true, &errors.ServiceError{ErrorDescription: errors.ErrClaimIsMalformed, ReferenceName: name}
+	}
+	return time.UnixMilli(int64(seconds * 1000)), true, nil
 }
@@ -0,0 +1,310 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/revocation"
+	"encoding/base64"
+	goerrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testIssuer      = "https://idp.example.com"
+	testOtherIssuer = "https://other-idp.example.com"
+	testClient      = "garm_client"
+)
+
+type testKeys struct {
+	rsa      *rsa.PrivateKey
+	otherRsa *rsa.PrivateKey
+	ec       *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, otherRsa: otherRsaKey, ec: ecKey}
+}
+
+// pemBody is a public key the way publicKeys holds it, without the markers.
+func pemBody(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func newTestService(t *testing.T, keys testKeys, tokenCache bool) *Service {
+	t.Helper()
+	cfg := &configs.Config{}
+	cfg.Logging = configs.Logging{Logger: "zerolog", Console: true, Level: "error"}
+	cfg.TokenCache = configs.TokenCache{Enabled: tokenCache, MaxEntries: 10, MaxTtl: time.Minute}
+	cfg.TrustedIssuers = []configs.TrustedIssuer{
+		{
+			Issuer:     testIssuer,
+			PublicKeys: map[string]string{testClient: pemBody(t, &keys.rsa.PublicKey)},
+			Audiences:  []string{testClient},
+			Algorithms: []string{"RS256", "ES256"},
+		},
+		{
+			Issuer:     testOtherIssuer,
+			PublicKeys: map[string]string{testClient: pemBody(t, &keys.otherRsa.PublicKey)},
+			Audiences:  []string{testClient},
+			Algorithms: []string{"RS256"},
+		},
+	}
+	revoked, err := revocation.NewList(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAuthenticationService(cfg, NewIssuers(cfg), revoked)
+}
+
+func testClaims(mutate func(claims jwt.MapClaims)) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testClient,
+		"sub":   "user-1",
+		"scope": "profile email",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+	}
+	if mutate != nil {
+		mutate(claims)
+	}
+	return claims
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims, key any) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func errorDescription(err error) string {
+	var serviceError *errors.ServiceError
+	if goerrors.As(err, &serviceError) {
+		return serviceError.ErrorDescription
+	}
+	return ""
+}
+
+func TestVerifyToken(t *testing.T) {
+	keys := newTestKeys(t)
+	service := newTestService(t, keys, false)
+	publicPem := "-----BEGIN PUBLIC KEY-----\n" + pemBody(t, &keys.rsa.PublicKey) + "\n-----END PUBLIC KEY-----"
+
+	valid := sign(t, jwt.SigningMethodRS256, testClaims(nil), keys.rsa)
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"valid RS256", valid, ""},
+		{
+			"alg none",
+			sign(t, jwt.SigningMethodNone, testClaims(nil), jwt.UnsafeAllowNoneSignatureType),
+			errors.ErrAlgorithmNotAllowed,
+		},
+		{
+			"HS256 with the public key as secret",
+			sign(t, jwt.SigningMethodHS256, testClaims(nil), []byte(publicPem)),
+			errors.ErrAlgorithmNotAllowed,
+		},
+		{
+			"HS256 with the bare public key as secret",
+			sign(t, jwt.SigningMethodHS256, testClaims(nil), []byte(pemBody(t, &keys.rsa.PublicKey))),
+			errors.ErrAlgorithmNotAllowed,
+		},
+		{
+			"algorithm outside the allow-list",
+			sign(t, jwt.SigningMethodRS384, testClaims(nil), keys.rsa),
+			errors.ErrAlgorithmNotAllowed,
+		},
+		{
+			"allowed algorithm that does not fit the key",
+			sign(t, jwt.SigningMethodES256, testClaims(nil), keys.ec),
+			errors.ErrAlgorithmKeyMismatch,
+		},
+		{
+			"signed by another key",
+			sign(t, jwt.SigningMethodRS256, testClaims(nil), keys.otherRsa),
+			errors.ErrSignatureIsInvalid,
+		},
+		{
+			"signed with the key of another issuer",
+			sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) { claims["iss"] = testOtherIssuer }), keys.rsa),
+			errors.ErrSignatureIsInvalid,
+		},
+		{
+			"tampered payload",
+			tamper(valid, testClaims(func(claims jwt.MapClaims) { claims["scope"] = "admin" })),
+			errors.ErrSignatureIsInvalid,
+		},
+		{
+			"untrusted issuer",
+			sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }), keys.rsa),
+			errors.ErrIssuerIsInvalid,
+		},
+		{
+			"issuer that only contains a trusted one",
+			sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) { claims["iss"] = testIssuer + ".evil.com" }), keys.rsa),
+			errors.ErrIssuerIsInvalid,
+		},
+		{
+			"expired",
+			sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }), keys.rsa),
+			errors.ErrTokenExpired,
+		},
+		{
+			"not yet valid",
+			sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) { claims["nbf"] = time.Now().Add(time.Hour).Unix() }), keys.rsa),
+			errors.ErrTokenNotYetValid,
+		},
+		{
+			"malformed",
+			"not.a-token",
+			errors.ErrTokenInvalid,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal, err := (&Tpl{Impl: service}).VerifyTokenTP("Bearer " + test.token)
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("want the token accepted, got %v", err)
+				}
+				if principal.ClientId != testClient || principal.Issuer != testIssuer {
+					t.Errorf("unexpected principal %+v", principal)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("want %q, the token was accepted", test.want)
+			}
+			if got := errorDescription(err); got != test.want {
+				t.Errorf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+// tamper swaps the payload of a signed token and keeps its signature.
+func tamper(token string, claims jwt.MapClaims) string {
+	parts := strings.Split(token, ".")
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SigningString()
+	return strings.Split(forged, ".")[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+}
+
+func TestVerifyTokenCache(t *testing.T) {
+	keys := newTestKeys(t)
+	service := newTestService(t, keys, true)
+	template := &Tpl{Impl: service}
+
+	token := sign(t, jwt.SigningMethodRS256, testClaims(func(claims jwt.MapClaims) {
+		claims["exp"] = time.Now().Add(1500 * time.Millisecond).Unix()
+	}), keys.rsa)
+	for i := 0; i < 2; i++ {
+		if _, err := template.VerifyTokenTP("Bearer " + token); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if _, found := service.verifiedClaims(token); !found {
+		t.Fatal("want the verified token cached")
+	}
+
+	forged := tamper(token, testClaims(func(claims jwt.MapClaims) { claims["scope"] = "admin" }))
+	if _, err := template.VerifyTokenTP("Bearer " + forged); errorDescription(err) != errors.ErrSignatureIsInvalid {
+		t.Errorf("a forged token must not ride on the cached one, got %v", err)
+	}
+
+	// The cache never outlives the token.
+	time.Sleep(2 * time.Second)
+	if _, found := service.verifiedClaims(token); found {
+		t.Error("want the cached claims gone after exp")
+	}
+	if _, err := template.VerifyTokenTP("Bearer " + token); errorDescription(err) != errors.ErrTokenExpired {
+		t.Errorf("want the expired token rejected, got %v", err)
+	}
+}
+
+func TestSigningMethod(t *testing.T) {
+	keys := newTestKeys(t)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA", "HS256", "none"}
+	tests := []struct {
+		name    string
+		alg     string
+		allowed []string
+		key     any
+		want    string
+	}{
+		{"RS256 with RSA", "RS256", all, &keys.rsa.PublicKey, ""},
+		{"PS256 with RSA", "PS256", all, &keys.rsa.PublicKey, ""},
+		{"ES256 with P-256", "ES256", all, &keys.ec.PublicKey, ""},
+		{"ES384 with P-384", "ES384", all, &p384.PublicKey, ""},
+		{"EdDSA with Ed25519", "EdDSA", all, edPublic, ""},
+		{"empty", "", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"none", "none", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"None in other case", "NoNe", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"HS256 even when allowed", "HS256", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"hs512 in lower case", "hs512", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"unknown", "XS256", all, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"not allowed", "RS512", []string{"RS256"}, &keys.rsa.PublicKey, errors.ErrAlgorithmNotAllowed},
+		{"ES256 with RSA", "ES256", all, &keys.rsa.PublicKey, errors.ErrAlgorithmKeyMismatch},
+		{"RS256 with EC", "RS256", all, &keys.ec.PublicKey, errors.ErrAlgorithmKeyMismatch},
+		{"ES384 with P-256", "ES384", all, &keys.ec.PublicKey, errors.ErrAlgorithmKeyMismatch},
+		{"ES256 with P-384", "ES256", all, &p384.PublicKey, errors.ErrAlgorithmKeyMismatch},
+		{"EdDSA with RSA", "EdDSA", all, &keys.rsa.PublicKey, errors.ErrAlgorithmKeyMismatch},
+		{"RS256 with Ed25519", "RS256", all, edPublic, errors.ErrAlgorithmKeyMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method, err := signingMethod(test.alg, test.allowed, test.key)
+			if test.want == "" {
+				if err != nil || method.Alg() != test.alg {
+					t.Fatalf("want %s, got %v", test.alg, err)
+				}
+				return
+			}
+			if got := errorDescription(err); got != test.want {
+				t.Errorf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -4,15 +4,18 @@ type Template interface {
 	extractToken(jwtToken string) (token string, err error)
 	getClaims(token string) (claimMap map[string]interface{}, err error)
 	isIssuerValid(claims map[string]interface{}) (bool, error)
-	isExpirationTimeValid(claims map[string]interface{}) (bool, error)
 	isSignatureValid(claims map[string]interface{}, token string) (bool, error)
+	isNotRevoked(claims map[string]interface{}) (bool, error)
+	isLifetimeValid(claims map[string]interface{}) (bool, error)
+	isAudienceValid(claims map[string]interface{}) (bool, error)
+	getPrincipal(claims map[string]interface{}) (*Principal, error)
 }
 
 type Tpl struct {
 	Impl Template
 }
 
-func (t *Tpl) VerifyTokenTP(jwtToken string) (map[string]interface{}, error) {
+func (t *Tpl) VerifyTokenTP(jwtToken string) (*Principal, error) {
 	var (
 		err      error
 		token    string
@@ -27,12 +30,18 @@ func (t *Tpl) VerifyTokenTP(jwtToken string) (map[string]interface{}, error) {
 	if _, err = t.Impl.isIssuerValid(claimMap); err != nil {
 		return nil, err
 	}
-	if _, err = t.Impl.isExpirationTimeValid(claimMap); err != nil {
+	if _, err = t.Impl.isSignatureValid(claimMap, token); err != nil {
 		return nil, err
 	}
-	if _, err = t.Impl.isSignatureValid(claimMap, token); err != nil {
+	if _, err = t.Impl.isNotRevoked(claimMap); err != nil {
+		return nil, err
+	}
+	if _, err = t.Impl.isLifetimeValid(claimMap); err != nil {
+		return nil, err
+	}
+	if _, err = t.Impl.isAudienceValid(claimMap); err != nil {
 		return nil, err
 	}
 
-	return claimMap, nil
+	return t.Impl.getPrincipal(claimMap)
 }
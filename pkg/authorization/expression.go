@@ -0,0 +1,465 @@
+package authorization
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are a small boolean language over the request environment:
+//
+//	claims.tenant == params.tenant && method in ["POST", "PUT"]
+//	!(topic startsWith "billing.") || "billing" in claims.groups
+//
+// References start with one of the known roots, literals are strings, numbers,
+// booleans, null and lists. Operators are ==, !=, in, contains, startsWith,
+// endsWith, !, && and ||. Every comparison with a reference that is missing
+// from the request is false, whatever the operator, and null equals nothing,
+// so that two absent values never match each other.
+const (
+	RootClaims  = "claims"
+	RootMethod  = "method"
+	RootPath    = "path"
+	RootParams  = "params"
+	RootHeaders = "headers"
+	RootTopic   = "topic"
+)
+
+var knownRoots = []string{RootClaims, RootMethod, RootPath, RootParams, RootHeaders, RootTopic}
+
+var comparisons = []string{"==", "!=", "in", "contains", "startsWith", "endsWith"}
+
+type Expression struct {
+	source string
+	root   node
+}
+
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at end of expression", p.peek().text)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) Evaluate(env map[string]any) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return value == true, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text := strings.NewReplacer(`\`+string(r), string(r), `\\`, `\`).Replace(string(runes[i+1 : j]))
+			tokens = append(tokens, token{kind: tokenString, text: text})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			if i+1 < len(runes) {
+				if pair := string(runes[i : i+2]); slices.Contains([]string{"==", "!=", "&&", "||"}, pair) {
+					tokens = append(tokens, token{kind: tokenPunct, text: pair})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("()[],.!", r) {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenPunct}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) accept(text string) bool {
+	if !p.done() && p.peek().kind != tokenString && p.peek().text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q but found %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right node
+		if right, err = p.parseAnd(); err == nil {
+			left = &binary{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept("&&") {
+		var right node
+		if right, err = p.parseNot(); err == nil {
+			left = &binary{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range comparisons {
+		if p.accept(op) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &binary{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return &literal{value: t.text}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return &literal{value: value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		return p.parseReference(t.text)
+	}
+
+	switch t.text {
+	case "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case "[":
+		items := &list{}
+		for !p.accept("]") {
+			if len(items.items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			items.items = append(items.items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *parser) parseReference(root string) (node, error) {
+	if !slices.Contains(knownRoots, root) {
+		return nil, fmt.Errorf("unknown identifier %q, expected one of %v", root, knownRoots)
+	}
+	ref := &reference{root: root}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.peek()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a name after %q", root)
+			}
+			p.pos++
+			ref.path = append(ref.path, t.text)
+		case p.accept("["):
+			t := p.peek()
+			if t.kind != tokenString {
+				return nil, fmt.Errorf("expected a quoted key after %q", root)
+			}
+			p.pos++
+			ref.path = append(ref.path, t.text)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			if root == RootHeaders {
+				for i := range ref.path {
+					ref.path[i] = strings.ToLower(ref.path[i])
+				}
+			}
+			return ref, nil
+		}
+	}
+}
+
+type node interface {
+	eval(env map[string]any) (any, error)
+}
+
+type literal struct {
+	value any
+}
+
+func (l *literal) eval(map[string]any) (any, error) {
+	return l.value, nil
+}
+
+type list struct {
+	items []node
+}
+
+func (l *list) eval(env map[string]any) (any, error) {
+	values := make([]any, 0, len(l.items))
+	for _, item := range l.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type reference struct {
+	root string
+	path []string
+}
+
+// missing is the value of a reference that does not resolve.
+type missing struct{}
+
+func (r *reference) eval(env map[string]any) (any, error) {
+	value, exists := env[r.root]
+	for _, key := range r.path {
+		if !exists {
+			break
+		}
+		switch m := value.(type) {
+		case map[string]any:
+			value, exists = m[key]
+		case map[string]string:
+			value, exists = m[key]
+		default:
+			exists = false
+		}
+	}
+	if !exists {
+		return missing{}, nil
+	}
+	return value, nil
+}
+
+type not struct {
+	operand node
+}
+
+func (n *not) eval(env map[string]any) (any, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return value != true, nil
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (b *binary) eval(env map[string]any) (any, error) {
+	left, err := b.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch b.op {
+	case "&&":
+		if left != true {
+			return false, nil
+		}
+		right, err := b.right.eval(env)
+		return right == true, err
+	case "||":
+		if left == true {
+			return true, nil
+		}
+		right, err := b.right.eval(env)
+		return right == true, err
+	}
+
+	right, err := b.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if left == (missing{}) || right == (missing{}) {
+		return false, nil
+	}
+	switch b.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	case "contains":
+		return contains(left, right), nil
+	case "startsWith":
+		l, lok := left.(string)
+		r, rok := right.(string)
+		return lok && rok && strings.HasPrefix(l, r), nil
+	case "endsWith":
+		l, lok := left.(string)
+		r, rok := right.(string)
+		return lok && rok && strings.HasSuffix(l, r), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", b.op)
+}
+
+func contains(container, item any) bool {
+	switch c := container.(type) {
+	case []any:
+		return slices.ContainsFunc(c, func(value any) bool { return equal(value, item) })
+	case []string:
+		return slices.ContainsFunc(c, func(value string) bool { return equal(value, item) })
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s)
+	}
+	return false
+}
+
+func equal(left, right any) bool {
+	if l, ok := number(left); ok {
+		r, ok := number(right)
+		return ok && l == r
+	}
+	switch left.(type) {
+	case string, bool:
+		return left == right
+	}
+	return false
+}
+
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,128 @@
+package authorization
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func testEnvironment() map[string]any {
+	return map[string]any{
+		RootClaims: map[string]any{
+			"tenant": "acme",
+			"groups": []any{"billing-publisher", "readers"},
+			"level":  json.Number("3"),
+			"admin":  true,
+			"empty":  nil,
+			"realm_access": map[string]any{
+				"roles": []any{"edge-admin"},
+			},
+		},
+		RootMethod:  "POST",
+		RootPath:    "/api/v1/tenants/acme/orders",
+		RootParams:  map[string]any{"tenant": "acme"},
+		RootHeaders: map[string]any{"x-tenant": "acme"},
+		RootTopic:   "billing.invoices",
+	}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+	}{
+		{"claim equals param", "claims.tenant == params.tenant", true},
+		{"header lookup is case insensitive", `headers["X-Tenant"] == "acme"`, true},
+		{"nested claim", `"edge-admin" in claims.realm_access.roles`, true},
+		{"not equal", `method != "GET"`, true},
+		{"number claim", "claims.level == 3", true},
+		{"bool claim", "claims.admin == true", true},
+		{"in list literal", `method in ["POST", "PUT"]`, true},
+		{"contains", `claims.groups contains "readers"`, true},
+		{"string contains", `path contains "/tenants/"`, true},
+		{"starts and ends with", `topic startsWith "billing." && topic endsWith ".invoices"`, true},
+
+		{"and binds tighter than or", `false && false || true`, true},
+		{"or is left of and", `true || false && false`, true},
+		{"parentheses override precedence", `(true || false) && false`, false},
+		{"not binds tighter than and", `!false && false`, false},
+		{"not of parentheses", `!(topic startsWith "billing.") || "billing-publisher" in claims.groups`, true},
+		{"double negation", `!!true`, true},
+
+		{"missing claims compare unequal", "claims.missing == params.missing", false},
+		{"missing claim and present param", "claims.missing == params.tenant", false},
+		{"missing header is not equal either", `headers["x-absent"] != "acme"`, false},
+		{"missing root path", "claims.tenant.name == \"acme\"", false},
+		{"missing item in list", `claims.missing in ["acme"]`, false},
+		{"missing reference is not true", "claims.missing", false},
+		{"null never equals null", "null == null", false},
+		{"null claim never equals null", "claims.empty == null", false},
+
+		{"string against number", `claims.tenant == 3`, false},
+		{"number against string", `claims.level == "3"`, false},
+		{"bool against string", `claims.admin == "true"`, false},
+		{"startsWith on a list", `claims.groups startsWith "billing"`, false},
+		{"in on a string item of a number list", `"1" in [1, 2]`, false},
+		{"contains on a number", `claims.level contains "3"`, false},
+		{"non boolean result", `claims.tenant`, false},
+	}
+	env := testEnvironment()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expression, err := CompileExpression(test.expression)
+			if err != nil {
+				t.Fatalf("compile %q: %v", test.expression, err)
+			}
+			got, err := expression.Evaluate(env)
+			if err != nil {
+				t.Fatalf("evaluate %q: %v", test.expression, err)
+			}
+			if got != test.want {
+				t.Errorf("%q = %v, want %v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExpressionEvaluateWithoutClaims(t *testing.T) {
+	env := testEnvironment()
+	env[RootClaims] = map[string]interface{}(nil)
+	expression, err := CompileExpression("claims.tenant == headers.tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _ := expression.Evaluate(env); allowed {
+		t.Error("an unauthenticated request without the header must not pass")
+	}
+}
+
+func TestCompileExpressionRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{"empty", ""},
+		{"unknown root", "token.tenant == params.tenant"},
+		{"unterminated string", `method == "POST`},
+		{"unexpected character", "method = 'POST'"},
+		{"dangling operator", "method =="},
+		{"dangling and", "true &&"},
+		{"unbalanced parenthesis", "(true || false"},
+		{"extra closing parenthesis", "true)"},
+		{"unclosed list", `method in ["POST"`},
+		{"list without separator", `method in ["POST" "PUT"]`},
+		{"trailing tokens", "true false"},
+		{"name missing after dot", "claims. == 1"},
+		{"unquoted index", "headers[tenant] == 1"},
+		{"unclosed index", `headers["tenant" == 1`},
+		{"invalid number", "claims.level == 1.2.3"},
+		{"lone not", "!"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := CompileExpression(test.expression); err == nil {
+				t.Errorf("%q compiled, want an error", test.expression)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package authorization
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"slices"
+	"strings"
+)
+
+const (
+	MatchAny = "any"
+	MatchAll = "all"
+)
+
+// Policy lists the scopes, roles and groups a caller needs. Match decides
+// whether any or all of them are required and applies to each list alike.
+type Policy struct {
+	Scopes []string
+	Roles  []string
+	Groups []string
+	Match  string
+}
+
+func NewPolicy(cfg configs.RoutePolicy) Policy {
+	return Policy{Scopes: cfg.Scopes, Roles: cfg.Roles, Groups: cfg.Groups, Match: cfg.Match}
+}
+
+func (p Policy) Check(scopeMap map[string]int, roles []string, groups []string) error {
+	if !p.satisfied(p.Scopes, func(scope string) bool { _, ok := scopeMap[scope]; return ok }) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrMissingRequiredScope, ExtraData: strings.Join(p.Scopes, " ")}
+	}
+	if !p.satisfied(p.Roles, func(role string) bool { return slices.Contains(roles, role) }) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrMissingRequiredRole, ExtraData: strings.Join(p.Roles, " ")}
+	}
+	if !p.satisfied(p.Groups, func(group string) bool { return slices.Contains(groups, group) }) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrMissingRequiredGroup, ExtraData: strings.Join(p.Groups, " ")}
+	}
+	return nil
+}
+
+func (p Policy) satisfied(required []string, granted func(string) bool) bool {
+	if len(required) == 0 {
+		return true
+	}
+	if strings.EqualFold(p.Match, MatchAll) {
+		for _, item := range required {
+			if !granted(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return slices.ContainsFunc(required, granted)
+}
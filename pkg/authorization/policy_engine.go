@@ -0,0 +1,97 @@
+package authorization
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/route"
+	goerrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Rule struct {
+	Name       string
+	Method     string
+	Path       string
+	expression *Expression
+}
+
+type PolicyEngine struct {
+	rules []Rule
+	topic string
+}
+
+// NewPolicyEngine compiles every rule up front and reports all broken rules
+// at once, so a typo in config stops the edge at startup instead of denying
+// requests at runtime.
+func NewPolicyEngine(cfg *configs.Config) (*PolicyEngine, error) {
+	engine := &PolicyEngine{topic: cfg.Kafka.Topic}
+	var errs []error
+	for i, item := range cfg.PolicyRules {
+		name := item.Name
+		if name == "" {
+			name = fmt.Sprintf("policyRules[%d]", i)
+		}
+		expression, err := CompileExpression(item.Expression)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy rule %s: %w", name, err))
+			continue
+		}
+		engine.rules = append(engine.rules, Rule{Name: name, Method: item.Method, Path: item.Path, expression: expression})
+	}
+	return engine, goerrors.Join(errs...)
+}
+
+func (e *PolicyEngine) Empty() bool {
+	return len(e.rules) == 0
+}
+
+// Evaluate requires every rule matching the route to hold.
+func (e *PolicyEngine) Evaluate(ctx *gin.Context) error {
+	var env map[string]any
+	for _, rule := range e.rules {
+		if !route.Match(rule.Method, rule.Path, ctx.Request.Method, route.Path(ctx)) {
+			continue
+		}
+		if env == nil {
+			env = e.environment(ctx)
+		}
+		allowed, err := rule.expression.Evaluate(env)
+		if err != nil || !allowed {
+			return &errors.ServiceError{ErrorDescription: errors.ErrPolicyDenied, ReferenceName: rule.Name}
+		}
+	}
+	return nil
+}
+
+func (e *PolicyEngine) environment(ctx *gin.Context) map[string]any {
+	claims, _ := ctx.Get(constant.Claims)
+	claimMap, _ := claims.(map[string]interface{})
+
+	params := map[string]any{}
+	for _, param := range ctx.Params {
+		params[param.Key] = param.Value
+	}
+	headers := map[string]any{}
+	for name, values := range ctx.Request.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+	topic := ctx.Param(RootTopic)
+	if topic == "" {
+		topic = e.topic
+	}
+
+	return map[string]any{
+		RootClaims:  claimMap,
+		RootMethod:  ctx.Request.Method,
+		RootPath:    ctx.Request.URL.Path,
+		RootParams:  params,
+		RootHeaders: headers,
+		RootTopic:   topic,
+	}
+}
@@ -0,0 +1,31 @@
+package authorization
+
+import (
+	"edge-app/configs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyService adds the expression rules of the PolicyEngine to the scope
+// and route policy checks of Service.
+type PolicyService struct {
+	*Service
+	engine *PolicyEngine
+}
+
+func NewPolicyService(cfg *configs.Config, engine *PolicyEngine) *PolicyService {
+	return &PolicyService{
+		Service: NewAuthorizationService(cfg),
+		engine:  engine,
+	}
+}
+
+func (s *PolicyService) hasRoutePolicy(ctx *gin.Context, scopeMap map[string]int) (ok bool, err error) {
+	if ok, err = s.Service.hasRoutePolicy(ctx, scopeMap); err != nil {
+		return ok, err
+	}
+	if err = s.engine.Evaluate(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -5,6 +5,8 @@ import (
 	"edge-app/pkg/constant"
 	"edge-app/pkg/errors"
 	"edge-app/pkg/logging"
+	"edge-app/pkg/route"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -56,3 +58,42 @@ func (s *Service) hasScope(ctx *gin.Context, scopeMap map[string]int) (ok bool,
 	}
 	return false, &errors.ServiceError{ErrorDescription: errors.ErrAccessForbidden}
 }
+
+func (s *Service) hasRoutePolicy(ctx *gin.Context, scopeMap map[string]int) (ok bool, err error) {
+	for _, item := range s.cfg.RoutePolicies {
+		if !route.Match(item.Method, item.Path, ctx.Request.Method, route.Path(ctx)) {
+			continue
+		}
+		if err = NewPolicy(item).Check(scopeMap, RetrieveRoles(ctx), RetrieveGroups(ctx)); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// hasRole requires one of the roles listed for the client in ValidRoles.
+// Clients without an entry are authorized by their scopes alone.
+func (s *Service) hasRole(ctx *gin.Context, roles []string) (ok bool, err error) {
+	validRole, exists := s.cfg.ValidRoles[ctx.GetString(constant.Aud)]
+	if !exists {
+		return true, nil
+	}
+	for _, item := range strings.Split(validRole, ",") {
+		if slices.Contains(roles, strings.TrimSpace(item)) {
+			return true, nil
+		}
+	}
+	return false, &errors.ServiceError{ErrorDescription: errors.ErrMissingRequiredRole, ExtraData: validRole}
+}
+
+func RetrieveRoles(ctx *gin.Context) []string {
+	roles, _ := ctx.Get(constant.Roles)
+	values, _ := roles.([]string)
+	return values
+}
+
+func RetrieveGroups(ctx *gin.Context) []string {
+	groups, _ := ctx.Get(constant.Groups)
+	values, _ := groups.([]string)
+	return values
+}
@@ -5,6 +5,8 @@ import "github.com/gin-gonic/gin"
 type Template interface {
 	retrieveScopes(ctx *gin.Context) (scopeMap map[string]int, err error)
 	hasScope(ctx *gin.Context, scopeMap map[string]int) (ok bool, err error)
+	hasRole(ctx *gin.Context, roles []string) (ok bool, err error)
+	hasRoutePolicy(ctx *gin.Context, scopeMap map[string]int) (ok bool, err error)
 }
 
 type Tpl struct {
@@ -23,6 +25,12 @@ func (t *Tpl) HasRole(ctx *gin.Context) (bool, error) {
 	if _, err = t.Impl.hasScope(ctx, scopeMap); err != nil {
 		return false, err
 	}
+	if _, err = t.Impl.hasRole(ctx, RetrieveRoles(ctx)); err != nil {
+		return false, err
+	}
+	if _, err = t.Impl.hasRoutePolicy(ctx, scopeMap); err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const defaultMaxEntries = 10000
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// LRU is a bounded, concurrency safe cache whose entries also expire after
+// their own ttl.
+type LRU[V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+func NewLRU[V any](maxEntries int) *LRU[V] {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return &LRU[V]{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      map[string]*list.Element{},
+	}
+}
+
+func (c *LRU[V]) Get(key string) (value V, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return value, false
+	}
+	ent := e.Value.(*entry[V])
+	if !time.Now().Before(ent.expiresAt) {
+		c.removeElement(e)
+		return value, false
+	}
+	c.ll.MoveToFront(e)
+	return ent.value, true
+}
+
+func (c *LRU[V]) Set(key string, value V, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeElement(e)
+	}
+	c.items[key] = c.ll.PushFront(&entry[V]{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+	for c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *LRU[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU[V]) removeElement(e *list.Element) {
+	c.ll.Remove(e)
+	delete(c.items, e.Value.(*entry[V]).key)
+}
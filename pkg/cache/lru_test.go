@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUGetSet(t *testing.T) {
+	c := NewLRU[int](10)
+	c.Set("a", 1, time.Minute)
+	if value, found := c.Get("a"); !found || value != 1 {
+		t.Fatalf("want 1, got %v %v", value, found)
+	}
+	c.Set("a", 2, time.Minute)
+	if value, _ := c.Get("a"); value != 2 || c.Len() != 1 {
+		t.Errorf("want the entry replaced, got %v with %d entries", value, c.Len())
+	}
+	c.Set("b", 3, 0)
+	if _, found := c.Get("b"); found {
+		t.Error("want entries without ttl not stored")
+	}
+	c.Delete("a")
+	if _, found := c.Get("a"); found {
+		t.Error("want the entry deleted")
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c := NewLRU[string](10)
+	c.Set("short", "x", 20*time.Millisecond)
+	c.Set("long", "y", time.Minute)
+	time.Sleep(30 * time.Millisecond)
+	if _, found := c.Get("short"); found {
+		t.Error("want the entry gone after its ttl")
+	}
+	if _, found := c.Get("long"); !found {
+		t.Error("want the other entry kept")
+	}
+	if c.Len() != 1 {
+		t.Errorf("want the expired entry dropped on read, got %d entries", c.Len())
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU[int](3)
+	for i, key := range []string{"a", "b", "c"} {
+		c.Set(key, i, time.Minute)
+	}
+	c.Get("a")
+	c.Set("d", 3, time.Minute)
+
+	if c.Len() != 3 {
+		t.Fatalf("want the cache bounded to 3, got %d", c.Len())
+	}
+	if _, found := c.Get("b"); found {
+		t.Error("want the least recently used entry evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("want %s kept", key)
+		}
+	}
+}
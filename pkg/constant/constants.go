@@ -2,14 +2,40 @@ package constant
 
 const (
 	AuthorizationKey string = "Authorization"
+	ApiKey           string = "X-API-Key"
 	Scope            string = "scope"
+	Roles            string = "roles"
+	Groups           string = "groups"
+	Claims           string = "claims"
+	AccessDecision   string = "accessDecision"
+	AccessPublic     string = "public"
+	AccessUnscoped   string = "unscoped"
+	AccessAuthorized string = "authorized"
+	AccessDenied     string = "denied"
+	AccessAllowed    string = "allowed"
+	AuthMode         string = "authMode"
 	Aud              string = "aud"
 	Exp              string = "exp"
+	Nbf              string = "nbf"
+	Iat              string = "iat"
 	Iss              string = "iss"
-	Issuer           string = "http://keycloak-ip:8080"
+	Jti              string = "jti"
+	Sub              string = "sub"
+	Azp              string = "azp"
+	ClientId         string = "client_id"
+	Username         string = "username"
+	Active           string = "active"
 	Metrics          string = "/metrics"
 	BeginPublicKey   string = "-----BEGIN PUBLIC KEY-----\n"
 	EndPublicKey     string = "\n-----END PUBLIC KEY-----"
+	IdempotencyKey   string = "Idempotency-Key"
+	IdempotentReplay string = "Idempotent-Replayed"
+	RequestTimeout   string = "Request-Timeout"
+	Signature        string = "X-Signature"
+	SignatureTime    string = "X-Signature-Timestamp"
+	SignatureNonce   string = "X-Signature-Nonce"
+	Deadline         string = "deadline"
+	AdminRole        string = "edge-admin"
 )
 
 type PaymentStatus int
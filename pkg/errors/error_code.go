@@ -28,14 +28,56 @@ const (
 	ErrUnexpectedError      = "unexpected error occurred !"
 	ErrClaimNotFound        = "claim not found !"
 	ErrTokenExpired         = "token expired !"
+	ErrTokenRevoked         = "token has been revoked !"
+	ErrRevocationInvalid    = "revocation entry is invalid !"
+	ErrRevocationUnknown    = "revocation entry not found !"
+	ErrRevocationInFile     = "revocation entry is managed in the revocation file !"
+	ErrInvalidClient        = "client authentication failed !"
+	ErrUnsupportedGrant     = "grant type is not supported !"
+	ErrInvalidScope         = "requested scope is invalid !"
+	ErrTokenNotYetValid     = "token is not valid yet !"
+	ErrTokenIssuedInFuture  = "token is issued in the future !"
+	ErrClaimIsMalformed     = "claim is malformed !"
 	ErrTokenInvalid         = "token is invalid !"
 	ErrClientIdNotFound     = "client id not found !"
 	ErrPublicKeyNotFound    = "public key not found !"
 	ErrPublicKeyIsInvalid   = "public key is invalid !"
 	ErrSignatureIsInvalid   = "signature is invalid !"
+	ErrSignatureMissing     = "request signature is missing !"
+	ErrSignatureStale       = "request timestamp is outside the allowed window !"
+	ErrNonceReused          = "request nonce has already been used !"
+	ErrNonceStoreFull       = "too many signed requests, retry later !"
+	ErrSigningKeyNotFound   = "no signing key is registered for this client !"
 	ErrIssuerIsInvalid      = "issuer is invalid !"
+	ErrAudienceIsInvalid    = "audience is invalid !"
+	ErrTokenInactive        = "token is not active !"
+	ErrIntrospectionFailed  = "token introspection failed !"
+	ErrAddressNotAllowed    = "client address is not allowed !"
+	ErrCertificateMissing   = "client certificate is missing !"
+	ErrCertificateInvalid   = "client certificate is invalid !"
+	ErrCertificateUnknown   = "client certificate is not registered !"
+	ErrApiKeyMissing        = "api key is missing !"
+	ErrApiKeyInvalid        = "api key is invalid !"
+	ErrApiKeyDisabled       = "api key is disabled !"
 	ErrScopeNotFound        = "scope not found !"
 	ErrAudNotFound          = "aud not found !"
 	ErrValidScopeNotDefined = "valid scope note defined in config file for this client !"
 	ErrAccessForbidden      = "you can not consume this service !"
+	ErrMissingRequiredScope = "required scope is missing !"
+	ErrMissingRequiredRole  = "required role is missing !"
+	ErrMissingRequiredGroup = "required group is missing !"
+	ErrPolicyDenied         = "request is denied by policy !"
+	ErrIdempotencyKeyReused = "idempotency key is already used for a different request !"
+	ErrRequestInProgress    = "a request with this idempotency key is in progress !"
+	ErrTooManyRequests      = "too many requests, try again later !"
+	ErrNotAcceptable        = "requested content type is not acceptable !"
+	ErrUnsupportedMediaType = "content type is not supported !"
+	ErrInvalidRequestBody   = "request body is invalid !"
+	ErrRequestBodyTooLarge  = "request body is too large !"
+	ErrInvalidTimeout       = "request timeout is invalid !"
+	ErrDeadlineExceeded     = "request deadline exceeded !"
+	ErrResponseUnreadable   = "response from the backend could not be read !"
+	ErrBrokerFailed         = "message broker did not deliver a response !"
+	ErrAlgorithmNotAllowed  = "signing algorithm is not allowed !"
+	ErrAlgorithmKeyMismatch = "signing algorithm does not match the key type !"
 )
@@ -0,0 +1,54 @@
+package idempotency
+
+import (
+	"edge-app/pkg/cache"
+	"sync"
+	"time"
+)
+
+type Record struct {
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type Store interface {
+	// Reserve stores a pending record for the key unless one already exists,
+	// in which case the existing record is returned and reserved is false.
+	Reserve(key string, fingerprint string, ttl time.Duration) (record *Record, reserved bool)
+	Complete(key string, record *Record, ttl time.Duration)
+	Release(key string)
+}
+
+func NewStore(maxEntries int) Store {
+	return &lruStore{records: cache.NewLRU[*Record](maxEntries)}
+}
+
+// lruStore keeps the records in a bounded LRU. Its own lock makes the lookup
+// and the reservation of Reserve a single step.
+type lruStore struct {
+	mu      sync.Mutex
+	records *cache.LRU[*Record]
+}
+
+func (s *lruStore) Reserve(key string, fingerprint string, ttl time.Duration) (*Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, found := s.records.Get(key); found {
+		return record, false
+	}
+	s.records.Set(key, &Record{Fingerprint: fingerprint}, ttl)
+	return nil, true
+}
+
+func (s *lruStore) Complete(key string, record *Record, ttl time.Duration) {
+	record.Completed = true
+	s.records.Set(key, record, ttl)
+}
+
+func (s *lruStore) Release(key string) {
+	s.records.Delete(key)
+}
@@ -0,0 +1,162 @@
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AssertionJwt  = "jwt"
+	AssertionHmac = "hmac"
+
+	defaultHeaderPrefix = "x-caller-"
+	defaultAssertionTtl = time.Minute
+)
+
+// Forwarder turns the verified caller of a request into Kafka headers, so
+// backends know who made the request without seeing the original token.
+//
+// With an assertion, backends can trust those headers: either a short-lived
+// HS256 JWT carrying the same identity, or an HMAC-SHA256 over the timestamp
+// followed by the name and value of every forwarded header in sorted order,
+// both signed with the shared secret. Each of those fields is written as
+// "<length>:<field>", so that no value can pass for a header boundary.
+type Forwarder struct {
+	cfg configs.IdentityForwarding
+}
+
+func NewForwarder(cfg *configs.Config) *Forwarder {
+	forwarding := cfg.IdentityForwarding
+	if forwarding.HeaderPrefix == "" {
+		forwarding.HeaderPrefix = defaultHeaderPrefix
+	}
+	if forwarding.Assertion.Ttl <= 0 {
+		forwarding.Assertion.Ttl = defaultAssertionTtl
+	}
+	return &Forwarder{cfg: forwarding}
+}
+
+func (f *Forwarder) Headers(ctx *gin.Context) ([]kafka.Header, error) {
+	if !f.cfg.Enabled {
+		return nil, nil
+	}
+	identity := f.identity(ctx)
+	if len(identity) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(identity))
+	for name := range identity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := make([]kafka.Header, 0, len(names)+2)
+	for _, name := range names {
+		headers = append(headers, kafka.Header{Key: f.cfg.HeaderPrefix + name, Value: []byte(identity[name])})
+	}
+
+	assertion, err := f.assertion(identity, names)
+	if err != nil {
+		return nil, err
+	}
+	return append(headers, assertion...), nil
+}
+
+func (f *Forwarder) identity(ctx *gin.Context) map[string]string {
+	identity := map[string]string{}
+	if clientId := ctx.GetString(constant.Aud); clientId != "" {
+		identity["client-id"] = clientId
+	}
+
+	claims, _ := ctx.Get(constant.Claims)
+	claimMap, _ := claims.(map[string]interface{})
+	for _, name := range f.cfg.Claims {
+		value := claimValue(claimMap[name])
+		switch {
+		case value != "":
+		case name == constant.Sub:
+			value = ctx.GetString(constant.Sub)
+		case name == constant.Scope:
+			value = ctx.GetString(constant.Scope)
+		}
+		if value != "" {
+			identity[name] = value
+		}
+	}
+	return identity
+}
+
+func (f *Forwarder) assertion(identity map[string]string, names []string) ([]kafka.Header, error) {
+	assertion := f.cfg.Assertion
+	if !assertion.Enabled {
+		return nil, nil
+	}
+	now := time.Now()
+
+	switch assertion.Type {
+	case AssertionHmac:
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(assertion.Secret))
+		writeField(mac, timestamp)
+		for _, name := range names {
+			writeField(mac, f.cfg.HeaderPrefix+name)
+			writeField(mac, identity[name])
+		}
+		return []kafka.Header{
+			{Key: f.cfg.HeaderPrefix + "timestamp", Value: []byte(timestamp)},
+			{Key: f.cfg.HeaderPrefix + "signature", Value: []byte(hex.EncodeToString(mac.Sum(nil)))},
+		}, nil
+	case "", AssertionJwt:
+		claims := jwt.MapClaims{
+			constant.Iss: assertion.Issuer,
+			constant.Iat: now.Unix(),
+			constant.Exp: now.Add(assertion.Ttl).Unix(),
+		}
+		for name, value := range identity {
+			if _, reserved := claims[name]; !reserved {
+				claims[name] = value
+			}
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(assertion.Secret))
+		if err != nil {
+			return nil, err
+		}
+		return []kafka.Header{{Key: f.cfg.HeaderPrefix + "assertion", Value: []byte(token)}}, nil
+	}
+	return nil, fmt.Errorf("unknown identity assertion type %q", assertion.Type)
+}
+
+// writeField writes a length-prefixed field to the signed input.
+func writeField(w io.Writer, field string) {
+	_, _ = io.WriteString(w, strconv.Itoa(len(field))+":"+field)
+}
+
+func claimValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,58 @@
+package identity
+
+import (
+	"edge-app/configs"
+	"testing"
+)
+
+func hmacForwarder() *Forwarder {
+	return NewForwarder(&configs.Config{IdentityForwarding: configs.IdentityForwarding{
+		Enabled:   true,
+		Assertion: configs.IdentityAssertion{Enabled: true, Type: AssertionHmac, Secret: "assertion-secret"},
+	}})
+}
+
+func signature(t *testing.T, f *Forwarder, identity map[string]string, names ...string) string {
+	t.Helper()
+	headers, err := f.assertion(identity, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, header := range headers {
+		if header.Key == f.cfg.HeaderPrefix+"signature" {
+			return string(header.Value)
+		}
+	}
+	t.Fatal("no signature header")
+	return ""
+}
+
+func TestHmacAssertionIsUnambiguous(t *testing.T) {
+	f := hmacForwarder()
+	tests := []struct {
+		name  string
+		one   map[string]string
+		other map[string]string
+		names [2][]string
+	}{
+		{
+			"value holding a second header",
+			map[string]string{"sub": "alice\nx-caller-tenant=acme"},
+			map[string]string{"sub": "alice", "tenant": "acme"},
+			[2][]string{{"sub"}, {"sub", "tenant"}},
+		},
+		{
+			"equals sign moved between name and value",
+			map[string]string{"sub": "a=b"},
+			map[string]string{"sub=a": "b"},
+			[2][]string{{"sub"}, {"sub=a"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if signature(t, f, test.one, test.names[0]...) == signature(t, f, test.other, test.names[1]...) {
+				t.Error("want different header sets signed differently")
+			}
+		})
+	}
+}
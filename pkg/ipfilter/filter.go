@@ -0,0 +1,135 @@
+package ipfilter
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/route"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Rule admits an address unless a deny range holds it, and when allow ranges
+// are listed, only if one of them does.
+type Rule struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+func NewRule(cfg configs.NetworkRule) (Rule, error) {
+	var (
+		rule Rule
+		err  error
+	)
+	if rule.allow, err = prefixes(cfg.Allow); err != nil {
+		return rule, err
+	}
+	rule.deny, err = prefixes(cfg.Deny)
+	return rule, err
+}
+
+// prefixes parses CIDR ranges, taking a bare address as a single host range.
+func prefixes(values []string) ([]netip.Prefix, error) {
+	result := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("network access: %w", err)
+			}
+			result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("network access: %w", err)
+		}
+		result = append(result, prefix.Masked())
+	}
+	return result, nil
+}
+
+func (r Rule) Permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(r.allow) == 0 {
+		return true
+	}
+	for _, prefix := range r.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type routeRule struct {
+	method string
+	path   string
+	rule   Rule
+}
+
+// Filter holds the global rule, the rules of the routes and those of the
+// clients. A request has to pass every one that applies to it.
+type Filter struct {
+	global  Rule
+	routes  []routeRule
+	clients map[string]Rule
+}
+
+func NewFilter(cfg *configs.Config) (*Filter, error) {
+	filter := &Filter{clients: map[string]Rule{}}
+	var err error
+	if filter.global, err = NewRule(cfg.NetworkAccess.NetworkRule); err != nil {
+		return nil, err
+	}
+	for _, item := range cfg.NetworkAccess.Routes {
+		rule, err := NewRule(item.NetworkRule)
+		if err != nil {
+			return nil, fmt.Errorf("route %s %s: %w", item.Method, item.Path, err)
+		}
+		filter.routes = append(filter.routes, routeRule{method: item.Method, path: item.Path, rule: rule})
+	}
+	for _, item := range cfg.NetworkAccess.Clients {
+		rule, err := NewRule(item.NetworkRule)
+		if err != nil {
+			return nil, fmt.Errorf("client %s: %w", item.ClientId, err)
+		}
+		filter.clients[item.ClientId] = rule
+	}
+	return filter, nil
+}
+
+// CheckRequest applies the global rule and the rules of matching routes.
+func (f *Filter) CheckRequest(method, path, ip string) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return &errors.ServiceError{ErrorDescription: errors.ErrAddressNotAllowed, OriginalValue: ip}
+	}
+	if !f.global.Permits(addr) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrAddressNotAllowed, OriginalValue: ip}
+	}
+	for _, item := range f.routes {
+		if route.Match(item.method, item.path, method, path) && !item.rule.Permits(addr) {
+			return &errors.ServiceError{ErrorDescription: errors.ErrAddressNotAllowed, OriginalValue: ip}
+		}
+	}
+	return nil
+}
+
+// CheckClient applies the registered ranges of an authenticated client.
+func (f *Filter) CheckClient(clientId, ip string) error {
+	rule, exists := f.clients[clientId]
+	if !exists {
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !rule.Permits(addr) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrAddressNotAllowed, OriginalValue: ip, ReferenceName: clientId}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package ipfilter
+
+import (
+	"edge-app/configs"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRulePermits(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  configs.NetworkRule
+		addr  string
+		allow bool
+	}{
+		{"no ranges", configs.NetworkRule{}, "203.0.113.7", true},
+		{"inside allow", configs.NetworkRule{Allow: []string{"10.0.0.0/8"}}, "10.1.2.3", true},
+		{"outside allow", configs.NetworkRule{Allow: []string{"10.0.0.0/8"}}, "11.0.0.1", false},
+		{"single address", configs.NetworkRule{Allow: []string{"192.0.2.10"}}, "192.0.2.10", true},
+		{"next to a single address", configs.NetworkRule{Allow: []string{"192.0.2.10"}}, "192.0.2.11", false},
+		{"unmasked range", configs.NetworkRule{Allow: []string{"10.1.2.3/16"}}, "10.1.200.1", true},
+		{"deny wins over allow", configs.NetworkRule{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.6.0.0/16"}}, "10.6.0.1", false},
+		{"deny only", configs.NetworkRule{Deny: []string{"198.51.100.0/24"}}, "198.51.100.99", false},
+		{"outside deny only", configs.NetworkRule{Deny: []string{"198.51.100.0/24"}}, "198.51.101.1", true},
+		{"IPv4 mapped IPv6", configs.NetworkRule{Allow: []string{"10.0.0.0/8"}}, "::ffff:10.0.0.1", true},
+		{"IPv6", configs.NetworkRule{Allow: []string{"2001:db8::/32"}}, "2001:db8::1", true},
+		{"IPv6 outside", configs.NetworkRule{Allow: []string{"2001:db8::/32"}}, "2001:db9::1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := NewRule(test.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.Permits(netip.MustParseAddr(test.addr)); got != test.allow {
+				t.Errorf("Permits(%s) = %v, want %v", test.addr, got, test.allow)
+			}
+		})
+	}
+}
+
+func TestNewRuleRejectsMalformedRanges(t *testing.T) {
+	for _, value := range []string{"10.0.0.0/33", "10.0.0", "example.com", "10.0.0.0/"} {
+		if _, err := NewRule(configs.NetworkRule{Allow: []string{value}}); err == nil {
+			t.Errorf("%q parsed, want an error", value)
+		}
+	}
+}
+
+func testFilter(t *testing.T) *Filter {
+	t.Helper()
+	cfg := &configs.Config{NetworkAccess: configs.NetworkAccess{
+		Enabled:        true,
+		TrustedProxies: []string{"10.0.0.1"},
+		NetworkRule:    configs.NetworkRule{Deny: []string{"198.51.100.0/24"}},
+		Routes: []configs.RouteNetworkAccess{
+			{Method: "GET", Path: "/api/v1/admin/*", NetworkRule: configs.NetworkRule{Allow: []string{"192.168.0.0/16"}}},
+		},
+		Clients: []configs.ClientNetworkAccess{
+			{ClientId: "garm_client", NetworkRule: configs.NetworkRule{Allow: []string{"203.0.113.0/24"}}},
+		},
+	}}
+	filter, err := NewFilter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+func TestFilterCheckRequest(t *testing.T) {
+	filter := testFilter(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		ip     string
+		allow  bool
+	}{
+		{"open route", "GET", "/api/v1/orders", "203.0.113.5", true},
+		{"globally denied", "GET", "/api/v1/orders", "198.51.100.5", false},
+		{"route from its range", "GET", "/api/v1/admin/keys", "192.168.1.1", true},
+		{"route from elsewhere", "GET", "/api/v1/admin/keys", "203.0.113.5", false},
+		{"route rule of another method", "POST", "/api/v1/admin/keys", "203.0.113.5", true},
+		{"globally denied inside the route range", "GET", "/api/v1/admin/keys", "198.51.100.5", false},
+		{"unparsable address", "GET", "/api/v1/orders", "unknown", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := filter.CheckRequest(test.method, test.path, test.ip)
+			if test.allow && err != nil {
+				t.Errorf("want allowed, got %v", err)
+			}
+			if !test.allow && err == nil {
+				t.Error("want denied")
+			}
+		})
+	}
+}
+
+func TestFilterCheckClient(t *testing.T) {
+	filter := testFilter(t)
+	tests := []struct {
+		name     string
+		clientId string
+		ip       string
+		allow    bool
+	}{
+		{"registered range", "garm_client", "203.0.113.9", true},
+		{"outside the registered range", "garm_client", "192.168.1.1", false},
+		{"unparsable address", "garm_client", "", false},
+		{"client without ranges", "other_client", "192.168.1.1", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := filter.CheckClient(test.clientId, test.ip)
+			if test.allow && err != nil {
+				t.Errorf("want allowed, got %v", err)
+			}
+			if !test.allow && err == nil {
+				t.Error("want denied")
+			}
+		})
+	}
+}
+
+// TestFilterForwardedFor checks the filter on the address gin resolves, which
+// only honours X-Forwarded-For when the peer is a trusted proxy.
+func TestFilterForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	filter := testFilter(t)
+	engine := gin.New()
+	if err := engine.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	engine.GET("/api/v1/admin/*any", func(c *gin.Context) {
+		if err := filter.CheckRequest(c.Request.Method, c.Request.URL.Path, c.ClientIP()); err != nil {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		peer   string
+		header string
+		status int
+	}{
+		{"direct from the range", "192.168.1.1:4000", "", http.StatusOK},
+		{"direct from elsewhere", "203.0.113.5:4000", "", http.StatusForbidden},
+		{"forwarded by a trusted proxy", "10.0.0.1:4000", "192.168.1.1", http.StatusOK},
+		{"denied client behind a trusted proxy", "10.0.0.1:4000", "203.0.113.5", http.StatusForbidden},
+		{"forged through an untrusted peer", "203.0.113.5:4000", "192.168.1.1", http.StatusForbidden},
+		{"forged chain through an untrusted peer", "203.0.113.5:4000", "192.168.1.1, 10.0.0.1", http.StatusForbidden},
+		{"forged hop prepended before a trusted proxy", "10.0.0.1:4000", "192.168.1.1, 203.0.113.5", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/keys", nil)
+			request.RemoteAddr = test.peer
+			if test.header != "" {
+				request.Header.Set("X-Forwarded-For", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, request)
+			if recorder.Code != test.status {
+				t.Errorf("want %d, got %d", test.status, recorder.Code)
+			}
+		})
+	}
+}
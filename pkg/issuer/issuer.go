@@ -0,0 +1,248 @@
+package issuer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"edge-app/configs"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	GrantClientCredentials = "client_credentials"
+
+	defaultTokenTtl = 5 * time.Minute
+)
+
+// Issuer is a minimal OAuth2 authorization server for local development and
+// tests. It signs client credentials tokens with one RSA or EC key, generated
+// at startup or loaded from KeyFile, and publishes it as a JWKS so the edge
+// verifies its own tokens like those of any other trusted issuer.
+type Issuer struct {
+	cfg     configs.DevIssuer
+	method  jwt.SigningMethod
+	key     crypto.Signer
+	keyId   string
+	clients map[string]configs.DevClient
+}
+
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+func NewIssuer(cfg *configs.Config) (*Issuer, error) {
+	issuer := &Issuer{cfg: cfg.DevIssuer, clients: map[string]configs.DevClient{}}
+	// The issuer is the iss of the tokens and the base of the endpoints, so
+	// discovery and tokens have to agree on it, trailing slash or not.
+	issuer.cfg.Issuer = strings.TrimSuffix(issuer.cfg.Issuer, "/")
+	if issuer.cfg.TokenTtl <= 0 {
+		issuer.cfg.TokenTtl = defaultTokenTtl
+	}
+	switch issuer.cfg.Algorithm {
+	case "", jwt.SigningMethodRS256.Alg():
+		issuer.method = jwt.SigningMethodRS256
+	case jwt.SigningMethodES256.Alg():
+		issuer.method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("dev issuer: unsupported algorithm %s", issuer.cfg.Algorithm)
+	}
+	for _, client := range issuer.cfg.Clients {
+		issuer.clients[client.ClientId] = client
+	}
+
+	var err error
+	if issuer.key, err = loadOrGenerateKey(issuer.cfg.KeyFile, issuer.method); err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(issuer.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := sha256.Sum256(der)
+	issuer.keyId = hex.EncodeToString(thumbprint[:8])
+	return issuer, nil
+}
+
+// Token runs the client credentials grant. Without a requested scope the
+// client gets all of its scopes, otherwise only a subset of them.
+func (i *Issuer) Token(grantType, clientId, clientSecret, scope string) (*Token, error) {
+	if grantType != GrantClientCredentials {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrUnsupportedGrant, OriginalValue: grantType}
+	}
+	client, exists := i.clients[clientId]
+	if !exists || subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrInvalidClient}
+	}
+	scopes := client.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, item := range requested {
+			if !slices.Contains(client.Scopes, item) {
+				return nil, &errors.ServiceError{ErrorDescription: errors.ErrInvalidScope, OriginalValue: item}
+			}
+		}
+		scopes = requested
+	}
+
+	audiences := client.Audiences
+	if len(audiences) == 0 {
+		audiences = []string{client.ClientId}
+	}
+	now := time.Now()
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, err
+	}
+	claims := jwt.MapClaims{
+		constant.Iss:   i.cfg.Issuer,
+		constant.Sub:   client.ClientId,
+		constant.Aud:   audiences,
+		constant.Azp:   client.ClientId,
+		constant.Scope: strings.Join(scopes, " "),
+		constant.Iat:   now.Unix(),
+		constant.Nbf:   now.Unix(),
+		constant.Exp:   now.Add(i.cfg.TokenTtl).Unix(),
+		constant.Jti:   hex.EncodeToString(jti),
+	}
+	if len(client.Roles) > 0 {
+		claims[constant.Roles] = client.Roles
+	}
+	token := jwt.NewWithClaims(i.method, claims)
+	token.Header["kid"] = i.keyId
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		return nil, err
+	}
+	return &Token{
+		AccessToken: signed,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(i.cfg.TokenTtl / time.Second),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Discovery is the subset of the OpenID provider metadata a resource server
+// needs, with endpoints below the issuer URL.
+func (i *Issuer) Discovery() map[string]interface{} {
+	base := i.cfg.Issuer
+	return map[string]interface{}{
+		"issuer":                                base,
+		"token_endpoint":                        base + "/token",
+		"jwks_uri":                              base + "/jwks",
+		"grant_types_supported":                 []string{GrantClientCredentials},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"id_token_signing_alg_values_supported": []string{i.method.Alg()},
+		"response_types_supported":              []string{"token"},
+		"subject_types_supported":               []string{"public"},
+	}
+}
+
+func (i *Issuer) Jwks() map[string]interface{} {
+	key := map[string]interface{}{
+		"kid": i.keyId,
+		"alg": i.method.Alg(),
+		"use": "sig",
+	}
+	switch public := i.key.Public().(type) {
+	case *rsa.PublicKey:
+		key["kty"] = "RSA"
+		key["n"] = encode(public.N.Bytes())
+		key["e"] = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		key["kty"] = "EC"
+		key["crv"] = public.Curve.Params().Name
+		key["x"] = encode(public.X.FillBytes(make([]byte, size)))
+		key["y"] = encode(public.Y.FillBytes(make([]byte, size)))
+	}
+	return map[string]interface{}{"keys": []interface{}{key}}
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// loadOrGenerateKey reads the PEM private key at path. When there is none yet
+// a key is generated and, if a path is set, written there so that tokens keep
+// verifying across restarts.
+func loadOrGenerateKey(path string, method jwt.SigningMethod) (crypto.Signer, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return parseKey(data, method)
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	var (
+		key crypto.Signer
+		err error
+	)
+	if method == jwt.SigningMethodES256 {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil || path == "" {
+		return key, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+}
+
+func parseKey(data []byte, method jwt.SigningMethod) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("dev issuer: key file is not PEM encoded")
+	}
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dev issuer: %w", err)
+	}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if method == jwt.SigningMethodRS256 {
+			return key, nil
+		}
+	case *ecdsa.PrivateKey:
+		if method == jwt.SigningMethodES256 && key.Curve == elliptic.P256() {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("dev issuer: key does not match algorithm %s", method.Alg())
+}
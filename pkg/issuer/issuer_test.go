@@ -0,0 +1,38 @@
+package issuer
+
+import (
+	"edge-app/configs"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestTokenIssuerMatchesDiscovery(t *testing.T) {
+	for _, configured := range []string{"http://localhost:8099/issuer", "http://localhost:8099/issuer/"} {
+		t.Run(configured, func(t *testing.T) {
+			issuer, err := NewIssuer(&configs.Config{DevIssuer: configs.DevIssuer{
+				Enabled: true,
+				Issuer:  configured,
+				Clients: []configs.DevClient{{ClientId: "dev_client", ClientSecret: "secret", Scopes: []string{"profile"}}},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := issuer.Token(GrantClientCredentials, "dev_client", "secret", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims := jwt.MapClaims{}
+			if _, _, err = new(jwt.Parser).ParseUnverified(token.AccessToken, claims); err != nil {
+				t.Fatal(err)
+			}
+			discovery := issuer.Discovery()
+			if claims["iss"] != discovery["issuer"] {
+				t.Errorf("token iss %v, discovery issuer %v", claims["iss"], discovery["issuer"])
+			}
+			if discovery["jwks_uri"] != "http://localhost:8099/issuer/jwks" {
+				t.Errorf("unexpected jwks_uri %v", discovery["jwks_uri"])
+			}
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package consumer
 
 import (
+	"context"
 	"edge-app/configs"
 )
 
 type Consumable interface {
 	Init()
-	Consume(topicName string) (msg interface{})
+	Consume(ctx context.Context, topicName string) (msg interface{}, err error)
 	Close()
 }
 
@@ -1,9 +1,11 @@
 package consumer
 
 import (
+	"context"
 	"edge-app/configs"
 	"edge-app/pkg/logging"
 	"edge-app/pkg/proto"
+	"errors"
 	"fmt"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
@@ -14,6 +16,9 @@ import (
 	"sync"
 )
 
+// ErrDeserialization marks a reply that arrived but could not be decoded.
+var ErrDeserialization = errors.New("failed to deserialize payload")
+
 var (
 	logger       logging.Logger
 	once         sync.Once
@@ -89,7 +94,7 @@ func (c *Consumer) Close() {
 	c.consumer.Close()
 }
 
-func (c *Consumer) Consume(topicName string) (payload interface{}) {
+func (c *Consumer) Consume(ctx context.Context, topicName string) (payload interface{}, err error) {
 
 	// Register the Protobuf type so that Deserialize can be called.
 	// An alternative is to pass a pointer to an instance of the Protobuf type
@@ -98,7 +103,7 @@ func (c *Consumer) Consume(topicName string) (payload interface{}) {
 
 	// Subscribe to topics, call the rebalancedCallback on assignment/revoke.
 	// The rebalancedCallback can be triggered from c.Poll() and c.Close().
-	err := c.consumer.SubscribeTopics([]string{topicName}, rebalancedCallback)
+	err = c.consumer.SubscribeTopics([]string{topicName}, rebalancedCallback)
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to subscribe topics: %s", err)
@@ -107,12 +112,15 @@ func (c *Consumer) Consume(topicName string) (payload interface{}) {
 
 	var msg *kafka.Message
 
-	run := true
-	for run {
+	// Polling stops as soon as the caller gives up, so an abandoned request
+	// does not keep the consumer busy.
+	for msg == nil {
 		select {
 		case sig := <-sigchan:
 			fmt.Printf("%% Caught signal %v: terminating\n", sig)
-			run = false
+			return nil, fmt.Errorf("consumer terminated by signal %v", sig)
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		default:
 			ev := c.consumer.Poll(100)
 			if ev == nil {
@@ -121,21 +129,20 @@ func (c *Consumer) Consume(topicName string) (payload interface{}) {
 			if msg, err = processEvent(c.consumer, ev); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to process event: %s\n", err)
 			}
-			run = false
 		}
 	}
 
 	payload, err = c.deserializer.Deserialize(*msg.TopicPartition.Topic, msg.Value)
 	if err != nil {
 		fmt.Printf("Failed to deserialize payload: %s\n", err)
-	} else {
-		fmt.Printf("%% Message on %s:\n%+v\n", msg.TopicPartition, payload)
+		return nil, fmt.Errorf("%w: %v", ErrDeserialization, err)
 	}
+	fmt.Printf("%% Message on %s:\n%+v\n", msg.TopicPartition, payload)
 	if msg.Headers != nil {
 		fmt.Printf("%% Headers: %v\n", msg.Headers)
 	}
 
-	return payload
+	return payload, nil
 }
 
 // processEvent processes the message/error received from the kafka Consumer's
@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"context"
 	"edge-app/configs"
 	"edge-app/pkg/logging"
 	"edge-app/pkg/proto"
@@ -84,7 +85,7 @@ func (p *Producer) Close() {
 	p.producer.Close()
 }
 
-func (p *Producer) Produce(topicName string, key string, payload *proto.PubSubReq, headers []kafka.Header) {
+func (p *Producer) Produce(ctx context.Context, topicName string, key string, payload *proto.PubSubReq, headers []kafka.Header) error {
 
 	// For signalling termination from main to go-routine
 	termChan := make(chan bool, 1)
@@ -157,7 +158,7 @@ func (p *Producer) Produce(topicName string, key string, payload *proto.PubSubRe
 	}()
 
 	msgcnt := 0
-	for run == true {
+	for run == true && ctx.Err() == nil {
 
 		message, err := p.serializer.Serialize(topicName, payload)
 		if err != nil {
@@ -197,7 +198,7 @@ func (p *Producer) Produce(topicName string, key string, payload *proto.PubSubRe
 	// Clean termination to get delivery results
 	// for all outstanding/in-transit/queued messages.
 	fmt.Printf("Flushing outstanding messages\n")
-	p.producer.Flush(15 * 1000)
+	p.producer.Flush(flushTimeoutMs(ctx))
 
 	// signal termination to go-routine
 	termChan <- true
@@ -210,4 +211,15 @@ func (p *Producer) Produce(topicName string, key string, payload *proto.PubSubRe
 	if fatalErr != nil {
 		os.Exit(1)
 	}
+
+	return ctx.Err()
+}
+
+// flushTimeoutMs bounds the flush by the caller deadline.
+func flushTimeoutMs(ctx context.Context) int {
+	timeout := 15 * time.Second
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = max(time.Until(deadline), 0)
+	}
+	return int(timeout / time.Millisecond)
 }
@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"context"
 	"edge-app/configs"
 	"edge-app/pkg/proto"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -8,7 +9,7 @@ import (
 
 type Producible interface {
 	Init()
-	Produce(topic string, key string, payload *proto.PubSubReq, headers []kafka.Header)
+	Produce(ctx context.Context, topic string, key string, payload *proto.PubSubReq, headers []kafka.Header) error
 	Close()
 }
 
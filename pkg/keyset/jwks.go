@@ -0,0 +1,226 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultRefreshInterval    = 15 * time.Minute
+	defaultMinRefreshInterval = 30 * time.Second
+	defaultFetchTimeout       = 5 * time.Second
+)
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwksDocument struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwksProvider struct {
+	source      configs.Jwks
+	client      *http.Client
+	logger      logging.Logger
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshes   singleflight.Group
+}
+
+func newJwksProvider(source configs.Jwks, logger logging.Logger) *jwksProvider {
+	if source.RefreshInterval <= 0 {
+		source.RefreshInterval = defaultRefreshInterval
+	}
+	if source.MinRefreshInterval <= 0 {
+		source.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	if source.Timeout <= 0 {
+		source.Timeout = defaultFetchTimeout
+	}
+	return &jwksProvider{
+		source: source,
+		client: &http.Client{Timeout: source.Timeout},
+		logger: logger,
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+func (p *jwksProvider) Key(kid string, _ string) (crypto.PublicKey, error) {
+	p.mu.RLock()
+	key, found := p.keys[kid]
+	stale := time.Since(p.fetchedAt) > p.source.RefreshInterval
+	p.mu.RUnlock()
+
+	if found {
+		// A known key stays usable while a newer set is being fetched.
+		if stale {
+			go p.refresh()
+		}
+		return key, nil
+	}
+	// An unknown kid usually means the identity provider rotated its keys,
+	// so the set is fetched again, at most once per MinRefreshInterval.
+	p.refresh()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if key, found = p.keys[kid]; found {
+		return key, nil
+	}
+	return nil, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyNotFound, OriginalValue: kid}
+}
+
+// refresh fetches the key set without holding the lock, so that a slow
+// endpoint never blocks lookups, and callers arriving meanwhile share the
+// same fetch.
+func (p *jwksProvider) refresh() {
+	_, _, _ = p.refreshes.Do(p.source.Url+p.source.FilePath, func() (interface{}, error) {
+		p.mu.Lock()
+		if time.Since(p.attemptedAt) < p.source.MinRefreshInterval {
+			p.mu.Unlock()
+			return nil, nil
+		}
+		p.attemptedAt = time.Now()
+		p.mu.Unlock()
+
+		keys, err := p.load()
+		if err != nil {
+			p.logger.Error(logging.Auth, logging.PublicKey, err.Error(), nil)
+			return nil, err
+		}
+		p.mu.Lock()
+		p.keys = keys
+		p.fetchedAt = time.Now()
+		p.mu.Unlock()
+		return nil, nil
+	})
+}
+
+func (p *jwksProvider) load() (map[string]crypto.PublicKey, error) {
+	data, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var document jwksDocument
+	if err = json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, item := range document.Keys {
+		if item.Use != "" && item.Use != "sig" {
+			continue
+		}
+		key, err := item.publicKey()
+		if err != nil {
+			p.logger.Warn(logging.Auth, logging.PublicKey, err.Error(), nil)
+			continue
+		}
+		keys[item.Kid] = key
+	}
+	return keys, nil
+}
+
+func (p *jwksProvider) read() ([]byte, error) {
+	if p.source.FilePath != "" {
+		return os.ReadFile(p.source.FilePath)
+	}
+
+	resp, err := p.client.Get(p.source.Url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint %s returned %d", p.source.Url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, supported := curves[k.Crv]
+		if !supported {
+			break
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("jwk point is not on curve %s for kid %q", k.Crv, k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			break
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwk ed25519 key has invalid size for kid %q", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported jwk key type %q for kid %q", k.Kty, k.Kid)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,211 @@
+package keyset
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"edge-app/configs"
+	"edge-app/pkg/logging"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLogger() logging.Logger {
+	return logging.NewLogger(&configs.Config{Logging: configs.Logging{Logger: "zerolog", Console: true, Level: "error"}})
+}
+
+func encode(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJwk(kid string, key *rsa.PublicKey) jwk {
+	return jwk{Kid: kid, Kty: "RSA", Use: "sig", N: encode(key.N), E: encode(big.NewInt(int64(key.E)))}
+}
+
+func ecJwk(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{Kid: kid, Kty: "EC", Crv: key.Curve.Params().Name, X: encode(key.X), Y: encode(key.Y)}
+}
+
+func TestJwkPublicKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	offCurve := ecJwk("off", &ecKey.PublicKey)
+	offCurve.Y = encode(new(big.Int).Add(ecKey.Y, big.NewInt(1)))
+	unknownCurve := ecJwk("curve", &ecKey.PublicKey)
+	unknownCurve.Crv = "P-192"
+	badBase64 := rsaJwk("bad", &rsaKey.PublicKey)
+	badBase64.N = "***"
+
+	tests := []struct {
+		name  string
+		key   jwk
+		valid bool
+	}{
+		{"RSA", rsaJwk("rsa", &rsaKey.PublicKey), true},
+		{"EC P-256", ecJwk("ec", &ecKey.PublicKey), true},
+		{"Ed25519", jwk{Kid: "ed", Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(edKey)}, true},
+		{"EC point off the curve", offCurve, false},
+		{"EC unknown curve", unknownCurve, false},
+		{"OKP other curve", jwk{Kid: "x", Kty: "OKP", Crv: "X25519", X: base64.RawURLEncoding.EncodeToString(edKey)}, false},
+		{"Ed25519 wrong size", jwk{Kid: "ed", Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(edKey[:16])}, false},
+		{"symmetric key", jwk{Kid: "oct", Kty: "oct"}, false},
+		{"invalid base64", badBase64, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := test.key.publicKey()
+			if test.valid && (err != nil || key == nil) {
+				t.Fatalf("want a key, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("want an error, got %T", key)
+			}
+		})
+	}
+}
+
+// jwksServer serves whatever document is current and counts the fetches.
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	document jwksDocument
+	delay    time.Duration
+	fetches  atomic.Int32
+}
+
+func newJwksServer(t *testing.T, keys ...jwk) *jwksServer {
+	s := &jwksServer{document: jwksDocument{Keys: keys}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		document, delay := s.document, s.delay
+		s.mu.Unlock()
+		time.Sleep(delay)
+		_ = json.NewEncoder(w).Encode(document)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) serve(delay time.Duration, keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.document, s.delay = jwksDocument{Keys: keys}, delay
+}
+
+func TestJwksProviderSelectsByKid(t *testing.T) {
+	signing, _ := rsa.GenerateKey(rand.Reader, 2048)
+	encryption, _ := rsa.GenerateKey(rand.Reader, 2048)
+	encryptionJwk := rsaJwk("enc", &encryption.PublicKey)
+	encryptionJwk.Use = "enc"
+	server := newJwksServer(t, rsaJwk("sig", &signing.PublicKey), encryptionJwk)
+
+	provider := newJwksProvider(configs.Jwks{Url: server.URL, MinRefreshInterval: time.Hour}, testLogger())
+	key, err := provider.Key("sig", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.(*rsa.PublicKey).Equal(&signing.PublicKey) {
+		t.Error("want the key of the requested kid")
+	}
+	if _, err = provider.Key("enc", ""); err == nil {
+		t.Error("want keys not meant for signatures skipped")
+	}
+	if _, err = provider.Key("unknown", ""); err == nil {
+		t.Error("want an unknown kid rejected")
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("want unknown kids refetched at most once per MinRefreshInterval, got %d fetches", fetches)
+	}
+}
+
+func TestJwksProviderRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJwksServer(t, rsaJwk("old", &oldKey.PublicKey))
+
+	provider := newJwksProvider(configs.Jwks{Url: server.URL, MinRefreshInterval: time.Millisecond}, testLogger())
+	if _, err := provider.Key("old", ""); err != nil {
+		t.Fatal(err)
+	}
+	server.serve(0, rsaJwk("new", &newKey.PublicKey))
+	time.Sleep(5 * time.Millisecond)
+	key, err := provider.Key("new", "")
+	if err != nil {
+		t.Fatalf("want the rotated key fetched, got %v", err)
+	}
+	if !key.(*rsa.PublicKey).Equal(&newKey.PublicKey) {
+		t.Error("want the rotated key")
+	}
+}
+
+func TestJwksProviderSlowEndpoint(t *testing.T) {
+	known, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server := newJwksServer(t, rsaJwk("known", &known.PublicKey))
+	provider := newJwksProvider(configs.Jwks{Url: server.URL, MinRefreshInterval: time.Millisecond}, testLogger())
+	if _, err := provider.Key("known", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	server.serve(300*time.Millisecond, rsaJwk("known", &known.PublicKey))
+	time.Sleep(5 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = provider.Key("unknown", "")
+		}()
+	}
+
+	// Known keys are served while the refresh is waiting on the endpoint.
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	if _, err := provider.Key("known", ""); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("a known key waited %v on the refresh", elapsed)
+	}
+	wg.Wait()
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("want concurrent refreshes collapsed into one fetch, got %d fetches", fetches-1)
+	}
+}
+
+func TestProviderFallsBackToStaticKeys(t *testing.T) {
+	jwksKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	staticKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	data, _ := json.Marshal(jwksDocument{Keys: []jwk{rsaJwk("jwks", &jwksKey.PublicKey)}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&staticKey.PublicKey)
+
+	provider := NewProvider(configs.Jwks{FilePath: path, MinRefreshInterval: time.Hour},
+		map[string]string{"garm_client": base64.StdEncoding.EncodeToString(der)}, testLogger())
+	if key, err := provider.Key("jwks", "garm_client"); err != nil || !key.(*rsa.PublicKey).Equal(&jwksKey.PublicKey) {
+		t.Errorf("want the JWKS key for its kid, got %v", err)
+	}
+	if key, err := provider.Key("unknown", "garm_client"); err != nil || !key.(*rsa.PublicKey).Equal(&staticKey.PublicKey) {
+		t.Errorf("want the static key of the client as fallback, got %v", err)
+	}
+	if _, err := provider.Key("unknown", "other_client"); err == nil {
+		t.Error("want no key for a client without one")
+	}
+}
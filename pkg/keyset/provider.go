@@ -0,0 +1,41 @@
+package keyset
+
+import (
+	"crypto"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+)
+
+type Provider interface {
+	// Key returns the verification key for the token key id. Sources that
+	// are not keyed by kid, such as static PEM keys, use the client id.
+	Key(kid string, clientId string) (crypto.PublicKey, error)
+}
+
+// NewProvider builds the key source of one issuer: its JWKS when configured,
+// falling back to the static PEM keys of the clients.
+func NewProvider(source configs.Jwks, publicKeys map[string]string, logger logging.Logger) Provider {
+	static := newStaticProvider(publicKeys)
+	if source.Url == "" && source.FilePath == "" {
+		return static
+	}
+	return &chainProvider{
+		providers: []Provider{newJwksProvider(source, logger), static},
+	}
+}
+
+type chainProvider struct {
+	providers []Provider
+}
+
+func (c *chainProvider) Key(kid string, clientId string) (crypto.PublicKey, error) {
+	var err error = &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyNotFound}
+	for _, provider := range c.providers {
+		var key crypto.PublicKey
+		if key, err = provider.Key(kid, clientId); err == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
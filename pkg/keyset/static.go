@@ -0,0 +1,59 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/x509"
+	"edge-app/pkg/constant"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/metrics"
+	"encoding/pem"
+	"sync"
+)
+
+type staticProvider struct {
+	publicKeys map[string]string
+	parsed     sync.Map
+}
+
+func newStaticProvider(publicKeys map[string]string) *staticProvider {
+	return &staticProvider{publicKeys: publicKeys}
+}
+
+// Key parses the PEM of a client once and keeps the result for later calls.
+func (s *staticProvider) Key(_ string, clientId string) (crypto.PublicKey, error) {
+	if key, found := s.parsed.Load(clientId); found {
+		metrics.AuthCache.WithLabelValues("public_key", "hit").Inc()
+		return key, nil
+	}
+	metrics.AuthCache.WithLabelValues("public_key", "miss").Inc()
+
+	publicKey, exist := s.publicKeys[clientId]
+	if !exist {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyNotFound}
+	}
+	key, err := ParsePublicKeyPEM([]byte(constant.BeginPublicKey + publicKey + constant.EndPublicKey))
+	if err != nil {
+		return nil, err
+	}
+	s.parsed.Store(clientId, key)
+	return key, nil
+}
+
+// ParsePublicKeyPEM accepts RSA, EC and Ed25519 keys in PKIX form as well as
+// certificates carrying one of them.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyIsInvalid}
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	return nil, &errors.ServiceError{ErrorDescription: errors.ErrPublicKeyIsInvalid}
+}
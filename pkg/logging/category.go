@@ -39,6 +39,9 @@ const (
 	OpenFile            SubCategory = "OpenFile"
 	Producer            SubCategory = "Producer"
 	Consumer            SubCategory = "Consumer"
+	Audit               SubCategory = "Audit"
+	Revocation          SubCategory = "Revocation"
+	Reload              SubCategory = "Reload"
 )
 
 const (
@@ -54,4 +57,5 @@ const (
 	RequestBody  ExtraKey = "RequestBody"
 	ResponseBody ExtraKey = "ResponseBody"
 	ErrorMessage ExtraKey = "ErrorMessage"
+	Access       ExtraKey = "Access"
 )
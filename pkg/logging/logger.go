@@ -1,6 +1,10 @@
 package logging
 
-import "edge-app/configs"
+import (
+	"edge-app/configs"
+
+	"github.com/rs/zerolog"
+)
 
 type Logger interface {
 	InitFileLogger(cfg *configs.Config)
@@ -28,3 +32,11 @@ func NewLogger(cfg *configs.Config) Logger {
 	}
 	panic("logger not supported")
 }
+
+// SetLevel applies the level of cfg to the logger already running, which is
+// all of the logging settings a reload can change.
+func SetLevel(cfg *configs.Config) {
+	if cfg.Logging.Logger == "zerolog" {
+		zerolog.SetGlobalLevel((&zeroLogger{cfg: cfg}).getLogLevel())
+	}
+}
@@ -8,3 +8,17 @@ var HttpCall = prometheus.NewCounterVec(
 		Help: "Number of Service calls",
 	}, []string{"path", "method", "status_code"},
 )
+
+var RateLimited = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Number of requests rejected by the rate limiter",
+	}, []string{"client", "path", "method"},
+)
+
+var AuthCache = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "auth_cache_lookups_total",
+		Help: "Number of authentication cache lookups",
+	}, []string{"cache", "result"},
+)
@@ -0,0 +1,45 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"edge-app/configs"
+	"fmt"
+	"os"
+)
+
+const (
+	ClientAuthNone          = "none"
+	ClientAuthVerifyIfGiven = "verify_if_given"
+	ClientAuthRequire       = "require"
+)
+
+// ServerConfig builds the TLS settings of the HTTP server. Client certificates
+// are verified against ClientCaFile; with verify_if_given, callers without a
+// certificate can still authenticate with a bearer token.
+func ServerConfig(cfg configs.Tls) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	switch cfg.ClientAuth {
+	case "", ClientAuthNone:
+		tlsConfig.ClientAuth = tls.NoClientCert
+		return tlsConfig, nil
+	case ClientAuthVerifyIfGiven:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q", cfg.ClientAuth)
+	}
+
+	data, err := os.ReadFile(cfg.ClientCaFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCaFile)
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
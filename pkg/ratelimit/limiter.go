@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	maxBuckets  = 100000
+	idleTimeout = 10 * time.Minute
+)
+
+type Rule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}}
+}
+
+func (l *Limiter) Allow(key string, rule Rule) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(rule.Burst)
+	b, exists := l.buckets[key]
+	if !exists {
+		if len(l.buckets) >= maxBuckets {
+			l.evictIdle(now)
+		}
+		b = &bucket{tokens: capacity, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.lastSeen).Seconds()*rule.RequestsPerSecond)
+	b.lastSeen = now
+
+	decision := Decision{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / rule.RequestsPerSecond)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = secondsToDuration((capacity - b.tokens) / rule.RequestsPerSecond)
+	return decision
+}
+
+func (l *Limiter) evictIdle(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
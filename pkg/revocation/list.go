@@ -0,0 +1,232 @@
+package revocation
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	TypeJti     = "jti"
+	TypeSubject = "sub"
+	TypeClient  = "client"
+
+	defaultRefreshInterval = 30 * time.Second
+)
+
+// Entry revokes every token carrying Value as its jti, subject or client id.
+// ExpiresAt is an optional RFC3339 time after which the entry is dropped,
+// typically the expiry of the longest lived token it targets.
+type Entry struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (e Entry) key() string {
+	return e.Type + ":" + e.Value
+}
+
+func (e Entry) expired(now time.Time) bool {
+	if e.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, e.ExpiresAt)
+	return err == nil && now.After(expiresAt)
+}
+
+func (e Entry) validate() error {
+	if e.Value == "" || (e.Type != TypeJti && e.Type != TypeSubject && e.Type != TypeClient) {
+		return &errors.ServiceError{ErrorDescription: errors.ErrRevocationInvalid, OriginalValue: e.key()}
+	}
+	if e.ExpiresAt != "" {
+		if _, err := time.Parse(time.RFC3339, e.ExpiresAt); err != nil {
+			return &errors.ServiceError{ErrorDescription: errors.ErrRevocationInvalid, ReferenceName: "expiresAt"}
+		}
+	}
+	return nil
+}
+
+// List is the deny-list shared by all authentication modes. Entries of the
+// file are replaced on every reload, while the ones added through the admin
+// endpoint or the Kafka topic are kept apart and survive it.
+type List struct {
+	mu      sync.RWMutex
+	cfg     configs.Revocation
+	logger  logging.Logger
+	file    map[string]Entry
+	dynamic map[string]Entry
+	modTime time.Time
+	topic   *topic
+}
+
+// NewList loads the file and starts its refresh and the Kafka subscription.
+// It is meant to be built once per process and shared by its users.
+func NewList(cfg *configs.Config) (*List, error) {
+	list := &List{
+		cfg:     cfg.Revocation,
+		logger:  logging.NewLogger(cfg),
+		file:    map[string]Entry{},
+		dynamic: map[string]Entry{},
+	}
+	if !cfg.Revocation.Enabled {
+		return list, nil
+	}
+	if list.cfg.RefreshInterval <= 0 {
+		list.cfg.RefreshInterval = defaultRefreshInterval
+	}
+	if list.cfg.FilePath != "" {
+		if err := list.reload(); err != nil {
+			return nil, err
+		}
+		go list.refresh()
+	}
+	if list.cfg.Kafka.Enabled {
+		var err error
+		if list.topic, err = newTopic(cfg, list); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Check fails when any of the identifiers of the caller has been revoked.
+// Empty identifiers are ignored.
+func (l *List) Check(jti, subject, clientId string) error {
+	if !l.cfg.Enabled {
+		return nil
+	}
+	now := time.Now()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, item := range []Entry{{Type: TypeJti, Value: jti}, {Type: TypeSubject, Value: subject}, {Type: TypeClient, Value: clientId}} {
+		if item.Value == "" {
+			continue
+		}
+		if entry, exists := l.lookup(item.key()); exists && !entry.expired(now) {
+			return &errors.ServiceError{ErrorDescription: errors.ErrTokenRevoked, ReferenceName: entry.Type}
+		}
+	}
+	return nil
+}
+
+func (l *List) lookup(key string) (Entry, bool) {
+	if entry, exists := l.dynamic[key]; exists {
+		return entry, true
+	}
+	entry, exists := l.file[key]
+	return entry, exists
+}
+
+// Entries lists the entries in effect, file entries first.
+func (l *List) Entries() []Entry {
+	now := time.Now()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]Entry, 0, len(l.file)+len(l.dynamic))
+	for _, source := range []map[string]Entry{l.file, l.dynamic} {
+		for _, entry := range source {
+			if !entry.expired(now) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// Add revokes on this instance and, through the Kafka topic, on every other.
+func (l *List) Add(entry Entry) error {
+	if err := entry.validate(); err != nil {
+		return err
+	}
+	if l.topic != nil {
+		if err := l.topic.publish(entry.key(), &entry); err != nil {
+			return err
+		}
+	}
+	l.apply(entry.key(), &entry)
+	return nil
+}
+
+// Remove lifts an entry added at runtime. Entries of the file can only be
+// removed from the file, so they are refused rather than reported lifted.
+func (l *List) Remove(entryType, value string) error {
+	key := Entry{Type: entryType, Value: value}.key()
+	l.mu.RLock()
+	_, dynamic := l.dynamic[key]
+	_, file := l.file[key]
+	l.mu.RUnlock()
+	if !dynamic {
+		if file {
+			return &errors.ServiceError{ErrorCode: errors.ErrDataMismatch, ErrorDescription: errors.ErrRevocationInFile, OriginalValue: key}
+		}
+		return &errors.ServiceError{ErrorCode: errors.ErrDataNotFound, ErrorDescription: errors.ErrRevocationUnknown, OriginalValue: key}
+	}
+	if l.topic != nil {
+		if err := l.topic.publish(key, nil); err != nil {
+			return err
+		}
+	}
+	l.apply(key, nil)
+	return nil
+}
+
+// apply stores an entry, or deletes it when entry is nil.
+func (l *List) apply(key string, entry *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry == nil {
+		delete(l.dynamic, key)
+		return
+	}
+	l.dynamic[key] = *entry
+}
+
+func (l *List) refresh() {
+	ticker := time.NewTicker(l.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := l.reload(); err != nil {
+			l.logger.Error(logging.Auth, logging.Revocation, err.Error(), nil)
+		}
+	}
+}
+
+// reload reads the file again when it has changed since the last load. On
+// error the entries loaded before stay in effect.
+func (l *List) reload() error {
+	info, err := os.Stat(l.cfg.FilePath)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(l.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(l.cfg.FilePath)
+	if err != nil {
+		return err
+	}
+	var fileEntries []Entry
+	if err = json.Unmarshal(data, &fileEntries); err != nil {
+		return fmt.Errorf("revocation file %s: %w", l.cfg.FilePath, err)
+	}
+	entries := map[string]Entry{}
+	for _, entry := range fileEntries {
+		if err = entry.validate(); err != nil {
+			return fmt.Errorf("revocation file %s: %w", l.cfg.FilePath, err)
+		}
+		entries[entry.key()] = entry
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.file = entries
+	l.modTime = info.ModTime()
+	return nil
+}
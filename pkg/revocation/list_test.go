@@ -0,0 +1,188 @@
+package revocation
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	goerrors "errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestList builds an enabled list without the refresh loop and the Kafka
+// topic.
+func newTestList(filePath string) *List {
+	return &List{
+		cfg:     configs.Revocation{Enabled: true, FilePath: filePath},
+		file:    map[string]Entry{},
+		dynamic: map[string]Entry{},
+	}
+}
+
+func errorDescription(err error) string {
+	var serviceError *errors.ServiceError
+	if goerrors.As(err, &serviceError) {
+		return serviceError.ErrorDescription
+	}
+	return ""
+}
+
+func errorCode(err error) int {
+	var serviceError *errors.ServiceError
+	if goerrors.As(err, &serviceError) {
+		return serviceError.ErrorCode
+	}
+	return -1
+}
+
+func TestListCheck(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	list := newTestList("")
+	for _, entry := range []Entry{
+		{Type: TypeJti, Value: "jti-1"},
+		{Type: TypeSubject, Value: "user-1", ExpiresAt: future},
+		{Type: TypeClient, Value: "old_client", ExpiresAt: past},
+	} {
+		if err := list.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		jti      string
+		subject  string
+		clientId string
+		revoked  bool
+	}{
+		{"nothing revoked", "jti-2", "user-2", "garm_client", false},
+		{"revoked jti", "jti-1", "user-2", "garm_client", true},
+		{"revoked subject", "jti-2", "user-1", "garm_client", true},
+		{"expired revocation entry", "jti-2", "user-2", "old_client", false},
+		{"value of another type", "user-1", "jti-1", "garm_client", false},
+		{"empty identifiers", "", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := list.Check(test.jti, test.subject, test.clientId)
+			if test.revoked && errorDescription(err) != errors.ErrTokenRevoked {
+				t.Errorf("want revoked, got %v", err)
+			}
+			if !test.revoked && err != nil {
+				t.Errorf("want allowed, got %v", err)
+			}
+		})
+	}
+
+	for _, entry := range list.Entries() {
+		if entry.Value == "old_client" {
+			t.Error("want the expired entry left out of the listing")
+		}
+	}
+}
+
+func TestListDisabled(t *testing.T) {
+	list := newTestList("")
+	list.apply("jti:jti-1", &Entry{Type: TypeJti, Value: "jti-1"})
+	list.cfg.Enabled = false
+	if err := list.Check("jti-1", "", ""); err != nil {
+		t.Errorf("want a disabled list to allow everything, got %v", err)
+	}
+}
+
+func TestEntryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		valid bool
+	}{
+		{"jti", Entry{Type: TypeJti, Value: "jti-1"}, true},
+		{"with expiry", Entry{Type: TypeClient, Value: "garm_client", ExpiresAt: "2030-01-01T00:00:00Z"}, true},
+		{"unknown type", Entry{Type: "email", Value: "a@b.c"}, false},
+		{"empty value", Entry{Type: TypeSubject}, false},
+		{"expiry not RFC3339", Entry{Type: TypeJti, Value: "jti-1", ExpiresAt: "tomorrow"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.entry.validate()
+			if test.valid && err != nil {
+				t.Errorf("want valid, got %v", err)
+			}
+			if !test.valid && errorDescription(err) != errors.ErrRevocationInvalid {
+				t.Errorf("want %q, got %v", errors.ErrRevocationInvalid, err)
+			}
+		})
+	}
+}
+
+func TestListReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := newTestList(path)
+	start := time.Now().Add(-time.Hour)
+
+	write(`[{"type":"sub","value":"user-1"}]`, start)
+	if err := list.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Add(Entry{Type: TypeJti, Value: "jti-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if list.Check("", "user-1", "") == nil || list.Check("jti-1", "", "") == nil {
+		t.Fatal("want the file and the dynamic entries in effect")
+	}
+
+	// The file replaces its own entries and leaves the dynamic ones alone.
+	write(`[{"type":"sub","value":"user-2"}]`, start.Add(time.Minute))
+	if err := list.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if list.Check("", "user-1", "") != nil || list.Check("", "user-2", "") == nil {
+		t.Error("want the entries of the file replaced")
+	}
+	if list.Check("jti-1", "", "") == nil {
+		t.Error("want the dynamic entry to survive the reload")
+	}
+
+	// An invalid file keeps the entries loaded before.
+	write(`[{"type":"email","value":"a@b.c"}]`, start.Add(2*time.Minute))
+	if err := list.reload(); err == nil {
+		t.Error("want the invalid file rejected")
+	}
+	if list.Check("", "user-2", "") == nil {
+		t.Error("want the previous entries kept after a failed reload")
+	}
+}
+
+func TestListRemove(t *testing.T) {
+	list := newTestList("")
+	list.file["sub:user-1"] = Entry{Type: TypeSubject, Value: "user-1"}
+	if err := list.Add(Entry{Type: TypeJti, Value: "jti-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Remove(TypeJti, "jti-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Check("jti-1", "", ""); err != nil {
+		t.Errorf("want the dynamic entry lifted, got %v", err)
+	}
+	if err := list.Remove(TypeJti, "jti-1"); errorCode(err) != errors.ErrDataNotFound {
+		t.Errorf("want an unknown entry reported, got %v", err)
+	}
+	if err := list.Remove(TypeSubject, "user-1"); errorCode(err) != errors.ErrDataMismatch {
+		t.Errorf("want the removal of a file entry refused, got %v", err)
+	}
+	if list.Check("", "user-1", "") == nil {
+		t.Error("want file entries kept, they can only be removed from the file")
+	}
+}
@@ -0,0 +1,120 @@
+package revocation
+
+import (
+	"edge-app/configs"
+	"edge-app/pkg/logging"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// topic shares the deny-list through a compacted Kafka topic keyed by
+// "<type>:<value>", where a tombstone lifts the entry. Every instance assigns
+// itself all partitions from the earliest offset and never commits, so a
+// restart replays the compacted state without leaving a consumer group
+// behind on the brokers.
+type topic struct {
+	name     string
+	list     *List
+	logger   logging.Logger
+	producer *kafka.Producer
+	consumer *kafka.Consumer
+}
+
+const metadataTimeout = 10000 // ms
+
+func newTopic(cfg *configs.Config, list *List) (*topic, error) {
+	name := cfg.Revocation.Kafka.Topic
+	// The client requires a group id, but without Subscribe or commits the
+	// group is never joined.
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Kafka.BootstrapServers,
+		"security.protocol":  cfg.Kafka.SecurityProtocol,
+		"group.id":           cfg.Kafka.GroupID + "-revocation",
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := consumer.GetMetadata(&name, false, metadataTimeout)
+	if err != nil {
+		return nil, err
+	}
+	partitions := make([]kafka.TopicPartition, 0, len(metadata.Topics[name].Partitions))
+	for _, partition := range metadata.Topics[name].Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &name, Partition: partition.ID, Offset: kafka.OffsetBeginning})
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("revocation topic %s has no partitions", name)
+	}
+	if err = consumer.Assign(partitions); err != nil {
+		return nil, err
+	}
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers": cfg.Kafka.BootstrapServers,
+		"security.protocol": cfg.Kafka.SecurityProtocol,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &topic{
+		name:     name,
+		list:     list,
+		logger:   list.logger,
+		producer: producer,
+		consumer: consumer,
+	}
+	go t.consume()
+	go t.deliveries()
+	return t, nil
+}
+
+func (t *topic) publish(key string, entry *Entry) error {
+	var value []byte
+	if entry != nil {
+		var err error
+		if value, err = json.Marshal(entry); err != nil {
+			return err
+		}
+	}
+	return t.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &t.name, Partition: kafka.PartitionAny},
+		Key:            []byte(key),
+		Value:          value,
+	}, nil)
+}
+
+func (t *topic) consume() {
+	for {
+		message, err := t.consumer.ReadMessage(time.Second)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); !ok || kafkaErr.Code() != kafka.ErrTimedOut {
+				t.logger.Error(logging.Kafka, logging.Revocation, err.Error(), nil)
+			}
+			continue
+		}
+		key := string(message.Key)
+		if len(message.Value) == 0 {
+			t.list.apply(key, nil)
+			continue
+		}
+		var entry Entry
+		if err = json.Unmarshal(message.Value, &entry); err != nil || entry.validate() != nil || entry.key() != key {
+			t.logger.Warn(logging.Kafka, logging.Revocation, "ignoring invalid revocation entry "+strings.TrimSpace(key), nil)
+			continue
+		}
+		t.list.apply(key, &entry)
+	}
+}
+
+func (t *topic) deliveries() {
+	for e := range t.producer.Events() {
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			t.logger.Error(logging.Kafka, logging.Revocation, m.TopicPartition.Error.Error(), nil)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package route
+
+import (
+	"edge-app/configs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Wildcard = "*"
+
+// Match reports whether the request method and path satisfy the configured
+// patterns, see configs.Route.Matches.
+func Match(methodPattern, pathPattern, method, path string) bool {
+	return configs.Route{Method: methodPattern, Path: pathPattern}.Matches(method, path)
+}
+
+// Path returns the registered route of the request, falling back to the raw
+// URL path when no route matched.
+func Path(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+	return c.Request.URL.Path
+}
+
+func MatchAny(routes []configs.Route, method, path string) bool {
+	for _, item := range routes {
+		if Match(item.Method, item.Path, method, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package signing
+
+import (
+	"container/heap"
+	"edge-app/pkg/errors"
+	"sync"
+	"time"
+)
+
+const defaultNonceMaxEntries = 100000
+
+// NonceStore remembers the nonces seen within the replay window.
+type NonceStore interface {
+	// Use records the nonce and reports false when it was already used. It
+	// fails when the nonce cannot be remembered for the whole ttl.
+	Use(key string, ttl time.Duration) (bool, error)
+}
+
+// memoryNonceStore keeps the nonces of this instance only. A nonce is never
+// dropped before its ttl ends, since it could be replayed from then on, so a
+// full store refuses new nonces instead.
+type memoryNonceStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	expiresAt  map[string]time.Time
+	expiries   nonceHeap
+}
+
+type nonce struct {
+	key       string
+	expiresAt time.Time
+}
+
+// nonceHeap orders nonces by expiry, so that expired ones are purged from
+// the top.
+type nonceHeap []nonce
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(nonce)) }
+func (h *nonceHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+func NewNonceStore(maxEntries int) NonceStore {
+	if maxEntries <= 0 {
+		maxEntries = defaultNonceMaxEntries
+	}
+	return &memoryNonceStore{maxEntries: maxEntries, expiresAt: map[string]time.Time{}}
+}
+
+func (s *memoryNonceStore) Use(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for s.expiries.Len() > 0 && !now.Before(s.expiries[0].expiresAt) {
+		delete(s.expiresAt, heap.Pop(&s.expiries).(nonce).key)
+	}
+	if _, used := s.expiresAt[key]; used {
+		return false, nil
+	}
+	if len(s.expiresAt) >= s.maxEntries {
+		return false, &errors.ServiceError{ErrorCode: errors.ErrServiceUnavailable, ErrorDescription: errors.ErrNonceStoreFull}
+	}
+	s.expiresAt[key] = now.Add(ttl)
+	heap.Push(&s.expiries, nonce{key: key, expiresAt: now.Add(ttl)})
+	return true, nil
+}
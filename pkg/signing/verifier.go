@@ -0,0 +1,134 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"edge-app/pkg/keyset"
+	"edge-app/pkg/route"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMaxSkew = 5 * time.Minute
+
+type client struct {
+	configs.SigningClient
+	publicKey crypto.PublicKey
+}
+
+// Verifier checks signed requests. A client signs the string built by
+// StringToSign with its HMAC secret, or with the private key matching its
+// registered RSA, EC or Ed25519 public key, and sends the timestamp, nonce
+// and base64 signature as headers.
+type Verifier struct {
+	cfg     configs.RequestSigning
+	clients map[string]*client
+	nonces  NonceStore
+}
+
+func NewVerifier(cfg *configs.Config, nonces NonceStore) (*Verifier, error) {
+	verifier := &Verifier{
+		cfg:     cfg.RequestSigning,
+		clients: map[string]*client{},
+		nonces:  nonces,
+	}
+	if verifier.cfg.MaxSkew <= 0 {
+		verifier.cfg.MaxSkew = defaultMaxSkew
+	}
+	for _, item := range cfg.RequestSigning.Clients {
+		entry := &client{SigningClient: item}
+		if item.PublicKey != "" {
+			key, err := keyset.ParsePublicKeyPEM([]byte(item.PublicKey))
+			if err != nil {
+				return nil, fmt.Errorf("request signing client %s: %w", item.ClientId, err)
+			}
+			entry.publicKey = key
+		} else if item.Secret == "" {
+			return nil, fmt.Errorf("request signing client %s: secret or publicKey is required", item.ClientId)
+		}
+		verifier.clients[item.ClientId] = entry
+	}
+	return verifier, nil
+}
+
+// Required reports whether the request must be signed, because its client
+// or its route demands it.
+func (v *Verifier) Required(clientId, method, path string) bool {
+	if client, exists := v.clients[clientId]; exists && client.Required {
+		return true
+	}
+	return route.MatchAny(v.cfg.Routes, method, path)
+}
+
+// StringToSign joins method, request URI, timestamp, nonce and the base64
+// sha256 digest of the body with newlines.
+func StringToSign(method, uri, timestamp, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{method, uri, timestamp, nonce, base64.StdEncoding.EncodeToString(digest[:])}, "\n")
+}
+
+// Verify rejects a stale timestamp before checking the signature, and only
+// consumes the nonce once the signature is valid so that forged requests
+// cannot burn the nonces of the client.
+func (v *Verifier) Verify(clientId, method, uri string, body []byte, timestamp, nonce, signature string) error {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return securityError(errors.ErrSignatureMissing)
+	}
+	client, exists := v.clients[clientId]
+	if !exists {
+		return securityError(errors.ErrSigningKeyNotFound)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return securityError(errors.ErrSignatureStale)
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > v.cfg.MaxSkew || skew < -v.cfg.MaxSkew {
+		return securityError(errors.ErrSignatureStale)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return securityError(errors.ErrSignatureIsInvalid)
+	}
+	if !client.verify([]byte(StringToSign(method, uri, timestamp, nonce, body)), decoded) {
+		return securityError(errors.ErrSignatureIsInvalid)
+	}
+	// A nonce has to be remembered as long as its timestamp can still pass.
+	fresh, err := v.nonces.Use(clientId+":"+nonce, 2*v.cfg.MaxSkew)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return securityError(errors.ErrNonceReused)
+	}
+	return nil
+}
+
+func (c *client) verify(message, signature []byte) bool {
+	digest := sha256.Sum256(message)
+	switch key := c.publicKey.(type) {
+	case nil:
+		mac := hmac.New(sha256.New, []byte(c.Secret))
+		mac.Write(message)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature)
+	}
+	return false
+}
+
+func securityError(description string) error {
+	return &errors.ServiceError{ErrorCode: errors.ErrSecurityError, ErrorDescription: description}
+}
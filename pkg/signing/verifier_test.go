@@ -0,0 +1,250 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"edge-app/configs"
+	"edge-app/pkg/errors"
+	"encoding/base64"
+	"encoding/pem"
+	goerrors "errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	testUri    = "/api/v1/?x=1"
+	testSecret = "billing-secret"
+)
+
+var testBody = []byte(`{"sequence":1}`)
+
+func publicPem(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+type signer func(message []byte) []byte
+
+func hmacSigner(secret string) signer {
+	return func(message []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(message)
+		return mac.Sum(nil)
+	}
+}
+
+func rsaSigner(key *rsa.PrivateKey) signer {
+	return func(message []byte) []byte {
+		digest := sha256.Sum256(message)
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		return signature
+	}
+}
+
+func ecdsaSigner(key *ecdsa.PrivateKey) signer {
+	return func(message []byte) []byte {
+		digest := sha256.Sum256(message)
+		signature, _ := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		return signature
+	}
+}
+
+func ed25519Signer(key ed25519.PrivateKey) signer {
+	return func(message []byte) []byte {
+		return ed25519.Sign(key, message)
+	}
+}
+
+type signedRequest struct {
+	clientId  string
+	method    string
+	uri       string
+	body      []byte
+	timestamp string
+	nonce     string
+	signature string
+}
+
+func newSignedRequest(clientId string, sign signer, nonce string, at time.Time) signedRequest {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	message := StringToSign("POST", testUri, timestamp, nonce, testBody)
+	return signedRequest{
+		clientId:  clientId,
+		method:    "POST",
+		uri:       testUri,
+		body:      testBody,
+		timestamp: timestamp,
+		nonce:     nonce,
+		signature: base64.StdEncoding.EncodeToString(sign([]byte(message))),
+	}
+}
+
+func (r signedRequest) verify(v *Verifier) error {
+	return v.Verify(r.clientId, r.method, r.uri, r.body, r.timestamp, r.nonce, r.signature)
+}
+
+func errorOf(err error) (string, int) {
+	var serviceError *errors.ServiceError
+	if goerrors.As(err, &serviceError) {
+		return serviceError.ErrorDescription, serviceError.ErrorCode
+	}
+	return "", -1
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherRsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	cfg := &configs.Config{RequestSigning: configs.RequestSigning{
+		Enabled: true,
+		MaxSkew: time.Minute,
+		Clients: []configs.SigningClient{
+			{ClientId: "hmac_client", Secret: testSecret},
+			{ClientId: "rsa_client", PublicKey: publicPem(t, &rsaKey.PublicKey)},
+			{ClientId: "ec_client", PublicKey: publicPem(t, &ecKey.PublicKey)},
+			{ClientId: "ed_client", PublicKey: publicPem(t, edPublic)},
+		},
+	}}
+	verifier, err := NewVerifier(cfg, NewNonceStore(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tampered := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-body", now)
+	tampered.body = []byte(`{"sequence":2}`)
+	otherUri := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-uri", now)
+	otherUri.uri = "/api/v1/?x=2"
+	otherMethod := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-method", now)
+	otherMethod.method = "PUT"
+	otherNonce := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-signed", now)
+	otherNonce.nonce = "n-swapped"
+	notBase64 := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-base64", now)
+	notBase64.signature = "***"
+	notNumeric := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-numeric", now)
+	notNumeric.timestamp = "yesterday"
+	unsigned := newSignedRequest("hmac_client", hmacSigner(testSecret), "n-unsigned", now)
+	unsigned.signature = ""
+	noNonce := newSignedRequest("hmac_client", hmacSigner(testSecret), "", now)
+
+	tests := []struct {
+		name    string
+		request signedRequest
+		want    string
+	}{
+		{"hmac", newSignedRequest("hmac_client", hmacSigner(testSecret), "n-1", now), ""},
+		{"rsa", newSignedRequest("rsa_client", rsaSigner(rsaKey), "n-1", now), ""},
+		{"ecdsa", newSignedRequest("ec_client", ecdsaSigner(ecKey), "n-1", now), ""},
+		{"ed25519", newSignedRequest("ed_client", ed25519Signer(edKey), "n-1", now), ""},
+		{"edge of the window", newSignedRequest("hmac_client", hmacSigner(testSecret), "n-edge", now.Add(-50*time.Second)), ""},
+		{"wrong secret", newSignedRequest("hmac_client", hmacSigner("guess"), "n-2", now), errors.ErrSignatureIsInvalid},
+		{"other rsa key", newSignedRequest("rsa_client", rsaSigner(otherRsaKey), "n-2", now), errors.ErrSignatureIsInvalid},
+		{"rsa key of another client", newSignedRequest("ec_client", rsaSigner(rsaKey), "n-2", now), errors.ErrSignatureIsInvalid},
+		{"tampered body", tampered, errors.ErrSignatureIsInvalid},
+		{"other uri", otherUri, errors.ErrSignatureIsInvalid},
+		{"other method", otherMethod, errors.ErrSignatureIsInvalid},
+		{"swapped nonce", otherNonce, errors.ErrSignatureIsInvalid},
+		{"signature not base64", notBase64, errors.ErrSignatureIsInvalid},
+		{"stale", newSignedRequest("hmac_client", hmacSigner(testSecret), "n-stale", now.Add(-2*time.Minute)), errors.ErrSignatureStale},
+		{"from the future", newSignedRequest("hmac_client", hmacSigner(testSecret), "n-future", now.Add(2*time.Minute)), errors.ErrSignatureStale},
+		{"timestamp not a number", notNumeric, errors.ErrSignatureStale},
+		{"missing signature", unsigned, errors.ErrSignatureMissing},
+		{"missing nonce", noNonce, errors.ErrSignatureMissing},
+		{"unknown client", newSignedRequest("nobody", hmacSigner(testSecret), "n-3", now), errors.ErrSigningKeyNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.request.verify(verifier)
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("want the request accepted, got %v", err)
+				}
+				return
+			}
+			description, code := errorOf(err)
+			if description != test.want || code != errors.ErrSecurityError {
+				t.Errorf("want %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsReplayedNonce(t *testing.T) {
+	cfg := &configs.Config{RequestSigning: configs.RequestSigning{
+		MaxSkew: time.Minute,
+		Clients: []configs.SigningClient{
+			{ClientId: "hmac_client", Secret: testSecret},
+			{ClientId: "other_client", Secret: testSecret},
+		},
+	}}
+	verifier, err := NewVerifier(cfg, NewNonceStore(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := newSignedRequest("hmac_client", hmacSigner(testSecret), "once", time.Now())
+	if err = request.verify(verifier); err != nil {
+		t.Fatal(err)
+	}
+	if description, _ := errorOf(request.verify(verifier)); description != errors.ErrNonceReused {
+		t.Errorf("want the replay rejected, got %q", description)
+	}
+	// A forged request must not burn the nonce of the client.
+	forged := newSignedRequest("hmac_client", hmacSigner("guess"), "unburnt", time.Now())
+	_ = forged.verify(verifier)
+	if err = newSignedRequest("hmac_client", hmacSigner(testSecret), "unburnt", time.Now()).verify(verifier); err != nil {
+		t.Errorf("want the nonce still usable after a forged attempt, got %v", err)
+	}
+	// Nonces are per client.
+	if err = newSignedRequest("other_client", hmacSigner(testSecret), "once", time.Now()).verify(verifier); err != nil {
+		t.Errorf("want another client free to use the same nonce, got %v", err)
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	store := NewNonceStore(2)
+	use := store.Use
+
+	if fresh, err := use("a", 50*time.Millisecond); !fresh || err != nil {
+		t.Fatalf("want a fresh nonce, got %v %v", fresh, err)
+	}
+	if fresh, err := use("a", time.Hour); fresh || err != nil {
+		t.Fatalf("want a reused nonce, got %v %v", fresh, err)
+	}
+	if fresh, err := use("b", time.Hour); !fresh || err != nil {
+		t.Fatalf("want a fresh nonce, got %v %v", fresh, err)
+	}
+
+	// Full of live nonces: nothing is evicted, new nonces are refused.
+	fresh, err := use("c", time.Hour)
+	if _, code := errorOf(err); fresh || code != errors.ErrServiceUnavailable {
+		t.Fatalf("want the full store to refuse, got %v %v", fresh, err)
+	}
+	if fresh, _ = use("b", time.Hour); fresh {
+		t.Fatal("want the live nonce kept while the store is full")
+	}
+
+	// Expired nonces make room and may be used again.
+	time.Sleep(60 * time.Millisecond)
+	if fresh, err = use("c", time.Hour); !fresh || err != nil {
+		t.Fatalf("want room after a nonce expired, got %v %v", fresh, err)
+	}
+	if fresh, err = use("a", time.Hour); fresh || err == nil {
+		t.Fatalf("want the store full again, got %v %v", fresh, err)
+	}
+}